@@ -15,15 +15,12 @@ package alchemyapi
 
 import (
 	"bytes"
-	"compress/gzip"
-	"encoding/json"
+	"context"
 	"errors"
-	"fmt"
-	"io"
-	"io/ioutil"
 	"net/http"
 	"net/url"
-	"strings"
+	"sync/atomic"
+	"time"
 )
 
 const (
@@ -37,8 +34,31 @@ var (
 
 // Analyzer
 type Analyzer struct {
-	apiKey  string
-	baseUrl string
+	apiKey    string
+	baseUrl   string
+	userAgent string
+	client    *http.Client
+	retry     RetryPolicy
+	limiter   *limiter
+	ledger    *Ledger
+	cache     Cache
+	cassette  *Cassette
+	logger    Logger
+	logBodies int
+	observers []Observer
+
+	interceptors []Interceptor
+	keyPool      *KeyPool
+	credentials  CredentialProvider
+
+	strictOptions bool
+	maxImageSize  int64
+	preprocessing *ImagePreprocessing
+	chunking      *ChunkOptions
+
+	maxResponseSize int64
+
+	cacheCounters cacheCounters
 }
 
 // initialize the entrypoints
@@ -112,9 +132,30 @@ func init() {
 	entryPoints.update("publication_date", "html", "/html/HTMLGetPubDate")
 }
 
-// Creates new Analyzer
-func NewAnalyzer(apiKey string) (*Analyzer, error) {
-	analyzer := &Analyzer{apiKey: apiKey, baseUrl: "http://access.alchemyapi.com/calls"}
+// Creates new Analyzer, the options are applied in order.
+//
+//	analyzer, err := NewAnalyzer(key,
+//		WithHTTPClient(&http.Client{Timeout: 10 * time.Second}),
+//		WithBaseURL("https://access.alchemyapi.com/calls"),
+//	)
+func NewAnalyzer(apiKey string, opts ...Option) (*Analyzer, error) {
+	analyzer := &Analyzer{
+		apiKey:    apiKey,
+		baseUrl:   DefaultBaseURL,
+		userAgent: DefaultUserAgent,
+		client:    &http.Client{},
+		ledger:    NewLedger(0, nil),
+
+		maxImageSize:    DefaultMaxImageSize,
+		maxResponseSize: DefaultMaxResponseSize,
+	}
+	for _, opt := range opts {
+		opt(analyzer)
+	}
+	if analyzer.cassette != nil {
+		analyzer.client = analyzer.cassette.wrap(analyzer.client)
+	}
+
 	if err := analyzer.validate(); err != nil {
 		return nil, err
 	} else {
@@ -122,8 +163,12 @@ func NewAnalyzer(apiKey string) (*Analyzer, error) {
 	}
 }
 
-//Validates the Api Key length
+//Validates the Api Key length, the key may be empty when drawn from a
+//KeyPool or a CredentialProvider
 func (analyzer *Analyzer) validate() error {
+	if (analyzer.keyPool != nil || analyzer.credentials != nil) && analyzer.apiKey == "" {
+		return nil
+	}
 	if len(analyzer.apiKey) != 40 {
 		return ApiKeyInvalid
 	} else {
@@ -132,6 +177,8 @@ func (analyzer *Analyzer) validate() error {
 }
 
 // Allow to reset the baseurl
+//
+// Deprecated: use the WithBaseURL option of NewAnalyzer.
 func (analyzer *Analyzer) SetBaseUrl(url string) {
 	analyzer.baseUrl = url
 }
@@ -154,29 +201,16 @@ func (analyzer *Analyzer) SetBaseUrl(url string) {
 
 */
 func (analyzer *Analyzer) Sentiment(flavor, payload string, options url.Values) (*SentimentResponse, error) {
-	if !entryPoints.hasFlavor("sentiment", flavor) {
-		return nil, errors.New(fmt.Sprintf("sentiment analysis for %s not available", flavor))
-	}
-
-	options.Add(flavor, payload)
-	url := entryPoints.urlFor(analyzer.baseUrl, "sentiment", flavor)
-	data, err := analyzer.analyze(url, options, nil)
+	return analyzer.SentimentContext(context.Background(), flavor, payload, options)
+}
 
-	if err != nil {
-		return nil, err
-	} else {
-		response := new(SentimentResponse)
-		err := json.Unmarshal(data, &response)
-		if err != nil {
-			return nil, err
-		} else {
-			if response.Status != "OK" {
-				return nil, errors.New(response.StatusInfo)
-			} else {
-				return response, nil
-			}
-		}
+// SentimentContext is the context-aware variant of Sentiment.
+func (analyzer *Analyzer) SentimentContext(ctx context.Context, flavor, payload string, options url.Values) (*SentimentResponse, error) {
+	if chunks := analyzer.chunks(flavor, payload); chunks != nil {
+		return analyzer.sentimentChunks(ctx, chunks, options)
 	}
+
+	return call(ctx, analyzer, sentimentEndpoint, flavor, payload, options, nil)
 }
 
 /*
@@ -197,34 +231,12 @@ func (analyzer *Analyzer) Sentiment(flavor, payload string, options url.Values)
 	The response, already converted from JSON to a SentimentResponse Object.
 */
 func (analyzer *Analyzer) SentimentTargeted(flavor, payload, target string, options url.Values) (*SentimentResponse, error) {
-	if target == "" {
-		return nil, errors.New("targeted sentiment requires a non-null target.")
-	}
-
-	if !entryPoints.hasFlavor("sentiment_targeted", flavor) {
-		return nil, errors.New(fmt.Sprintf("sentiment targeted analysis for %s not available", flavor))
-	}
-
-	options.Add(flavor, payload)
-	options.Add("target", target)
-	url := entryPoints.urlFor(analyzer.baseUrl, "sentiment_targeted", flavor)
-	data, err := analyzer.analyze(url, options, nil)
+	return analyzer.SentimentTargetedContext(context.Background(), flavor, payload, target, options)
+}
 
-	if err != nil {
-		return nil, err
-	} else {
-		response := new(SentimentResponse)
-		err := json.Unmarshal(data, &response)
-		if err != nil {
-			return nil, err
-		} else {
-			if response.Status != "OK" {
-				return nil, errors.New(response.StatusInfo)
-			} else {
-				return response, nil
-			}
-		}
-	}
+// SentimentTargetedContext is the context-aware variant of SentimentTargeted.
+func (analyzer *Analyzer) SentimentTargetedContext(ctx context.Context, flavor, payload, target string, options url.Values) (*SentimentResponse, error) {
+	return call(ctx, analyzer, sentimentTargetedEndpoint, flavor, payload, options, url.Values{"target": {target}})
 }
 
 /*
@@ -244,29 +256,12 @@ func (analyzer *Analyzer) SentimentTargeted(flavor, payload, target string, opti
 	The response, already converted from JSON to a TaxonomyResponse object.
 */
 func (analyzer *Analyzer) Taxonomy(flavor, payload string, options url.Values) (*TaxonomyResponse, error) {
-	if !entryPoints.hasFlavor("taxonomy", flavor) {
-		return nil, errors.New(fmt.Sprintf("Taxonomy info for %s not available", flavor))
-	}
-
-	options.Add(flavor, payload)
-	url := entryPoints.urlFor(analyzer.baseUrl, "taxonomy", flavor)
-	data, err := analyzer.analyze(url, options, nil)
+	return analyzer.TaxonomyContext(context.Background(), flavor, payload, options)
+}
 
-	if err != nil {
-		return nil, err
-	} else {
-		response := new(TaxonomyResponse)
-		err := json.Unmarshal(data, &response)
-		if err != nil {
-			return nil, err
-		} else {
-			if response.Status != "OK" {
-				return nil, errors.New(response.StatusInfo)
-			} else {
-				return response, nil
-			}
-		}
-	}
+// TaxonomyContext is the context-aware variant of Taxonomy.
+func (analyzer *Analyzer) TaxonomyContext(ctx context.Context, flavor, payload string, options url.Values) (*TaxonomyResponse, error) {
+	return call(ctx, analyzer, taxonomyEndpoint, flavor, payload, options, nil)
 }
 
 /*
@@ -288,29 +283,16 @@ func (analyzer *Analyzer) Taxonomy(flavor, payload string, options url.Values) (
    The response, already converted from JSON to a ConceptsResponse.
 */
 func (analyzer *Analyzer) Concepts(flavor, payload string, options url.Values) (*ConceptsResponse, error) {
-	if !entryPoints.hasFlavor("concepts", flavor) {
-		return nil, errors.New(fmt.Sprintf("concepts info for %s not available", flavor))
-	}
-
-	options.Add(flavor, payload)
-	url := entryPoints.urlFor(analyzer.baseUrl, "concepts", flavor)
-	data, err := analyzer.analyze(url, options, nil)
+	return analyzer.ConceptsContext(context.Background(), flavor, payload, options)
+}
 
-	if err != nil {
-		return nil, err
-	} else {
-		response := new(ConceptsResponse)
-		err := json.Unmarshal(data, &response)
-		if err != nil {
-			return nil, err
-		} else {
-			if response.Status != "OK" {
-				return nil, errors.New(response.StatusInfo)
-			} else {
-				return response, nil
-			}
-		}
+// ConceptsContext is the context-aware variant of Concepts.
+func (analyzer *Analyzer) ConceptsContext(ctx context.Context, flavor, payload string, options url.Values) (*ConceptsResponse, error) {
+	if chunks := analyzer.chunks(flavor, payload); chunks != nil {
+		return analyzer.conceptsChunks(ctx, chunks, options)
 	}
+
+	return call(ctx, analyzer, conceptsEndpoint, flavor, payload, options, nil)
 }
 
 /*
@@ -336,29 +318,16 @@ func (analyzer *Analyzer) Concepts(flavor, payload string, options url.Values) (
    The response, already converted from JSON to a EntitiesResponse object.
 */
 func (analyzer *Analyzer) Entities(flavor, payload string, options url.Values) (*EntitiesResponse, error) {
-	if !entryPoints.hasFlavor("entities", flavor) {
-		return nil, errors.New(fmt.Sprintf("entities info for %s not available", flavor))
-	}
-
-	options.Add(flavor, payload)
-	url := entryPoints.urlFor(analyzer.baseUrl, "entities", flavor)
-	data, err := analyzer.analyze(url, options, nil)
+	return analyzer.EntitiesContext(context.Background(), flavor, payload, options)
+}
 
-	if err != nil {
-		return nil, err
-	} else {
-		response := new(EntitiesResponse)
-		err := json.Unmarshal(data, &response)
-		if err != nil {
-			return nil, err
-		} else {
-			if response.Status != "OK" {
-				return nil, errors.New(response.StatusInfo)
-			} else {
-				return response, nil
-			}
-		}
+// EntitiesContext is the context-aware variant of Entities.
+func (analyzer *Analyzer) EntitiesContext(ctx context.Context, flavor, payload string, options url.Values) (*EntitiesResponse, error) {
+	if chunks := analyzer.chunks(flavor, payload); chunks != nil {
+		return analyzer.entitiesChunks(ctx, chunks, options)
 	}
+
+	return call(ctx, analyzer, entitiesEndpoint, flavor, payload, options, nil)
 }
 
 /*
@@ -381,29 +350,16 @@ func (analyzer *Analyzer) Entities(flavor, payload string, options url.Values) (
    The response, already converted from JSON to a KeywordsResponse object.
 */
 func (analyzer *Analyzer) Keywords(flavor, payload string, options url.Values) (*KeywordsResponse, error) {
-	if !entryPoints.hasFlavor("keywords", flavor) {
-		return nil, errors.New(fmt.Sprintf("keywords info for %s not available", flavor))
-	}
-
-	options.Add(flavor, payload)
-	url := entryPoints.urlFor(analyzer.baseUrl, "keywords", flavor)
-	data, err := analyzer.analyze(url, options, nil)
+	return analyzer.KeywordsContext(context.Background(), flavor, payload, options)
+}
 
-	if err != nil {
-		return nil, err
-	} else {
-		response := new(KeywordsResponse)
-		err := json.Unmarshal(data, &response)
-		if err != nil {
-			return nil, err
-		} else {
-			if response.Status != "OK" {
-				return nil, errors.New(response.StatusInfo)
-			} else {
-				return response, nil
-			}
-		}
+// KeywordsContext is the context-aware variant of Keywords.
+func (analyzer *Analyzer) KeywordsContext(ctx context.Context, flavor, payload string, options url.Values) (*KeywordsResponse, error) {
+	if chunks := analyzer.chunks(flavor, payload); chunks != nil {
+		return analyzer.keywordsChunks(ctx, chunks, options)
 	}
+
+	return call(ctx, analyzer, keywordsEndpoint, flavor, payload, options, nil)
 }
 
 /*
@@ -432,29 +388,12 @@ func (analyzer *Analyzer) Keywords(flavor, payload string, options url.Values) (
    The response, already converted from JSON to a Relation object.
 */
 func (analyzer *Analyzer) Relations(flavor, payload string, options url.Values) (*RelationsResponse, error) {
-	if !entryPoints.hasFlavor("relations", flavor) {
-		return nil, errors.New(fmt.Sprintf("relations info for %s not available", flavor))
-	}
-
-	options.Add(flavor, payload)
-	url := entryPoints.urlFor(analyzer.baseUrl, "relations", flavor)
-	data, err := analyzer.analyze(url, options, nil)
+	return analyzer.RelationsContext(context.Background(), flavor, payload, options)
+}
 
-	if err != nil {
-		return nil, err
-	} else {
-		response := new(RelationsResponse)
-		err := json.Unmarshal(data, &response)
-		if err != nil {
-			return nil, err
-		} else {
-			if response.Status != "OK" {
-				return nil, errors.New(response.StatusInfo)
-			} else {
-				return response, nil
-			}
-		}
-	}
+// RelationsContext is the context-aware variant of Relations.
+func (analyzer *Analyzer) RelationsContext(ctx context.Context, flavor, payload string, options url.Values) (*RelationsResponse, error) {
+	return call(ctx, analyzer, relationsEndpoint, flavor, payload, options, nil)
 }
 
 /*
@@ -475,83 +414,32 @@ func (analyzer *Analyzer) Relations(flavor, payload string, options url.Values)
    The response, already converted from JSON to a Title & Text Response object.
 */
 func (analyzer *Analyzer) Text(flavor, payload string, options url.Values) (*TextTitleResponse, error) {
-	if !entryPoints.hasFlavor("text", flavor) {
-		return nil, errors.New(fmt.Sprintf("text info for %s not available", flavor))
-	}
-
-	options.Add(flavor, payload)
-	url := entryPoints.urlFor(analyzer.baseUrl, "text", flavor)
-	data, err := analyzer.analyze(url, options, nil)
+	return analyzer.TextContext(context.Background(), flavor, payload, options)
+}
 
-	if err != nil {
-		return nil, err
-	} else {
-		response := new(TextTitleResponse)
-		err := json.Unmarshal(data, &response)
-		if err != nil {
-			return nil, err
-		} else {
-			if response.Status != "OK" {
-				return nil, errors.New(response.StatusInfo)
-			} else {
-				return response, nil
-			}
-		}
-	}
+// TextContext is the context-aware variant of Text.
+func (analyzer *Analyzer) TextContext(ctx context.Context, flavor, payload string, options url.Values) (*TextTitleResponse, error) {
+	return call(ctx, analyzer, textEndpoint, flavor, payload, options, nil)
 }
 
 // see Text
 func (analyzer *Analyzer) TextRaw(flavor, payload string, options url.Values) (*TextTitleResponse, error) {
-	if !entryPoints.hasFlavor("text_raw", flavor) {
-		return nil, errors.New(fmt.Sprintf("text_raw info for %s not available", flavor))
-	}
-
-	options.Add(flavor, payload)
-	url := entryPoints.urlFor(analyzer.baseUrl, "text_raw", flavor)
-	data, err := analyzer.analyze(url, options, nil)
+	return analyzer.TextRawContext(context.Background(), flavor, payload, options)
+}
 
-	if err != nil {
-		return nil, err
-	} else {
-		response := new(TextTitleResponse)
-		err := json.Unmarshal(data, &response)
-		if err != nil {
-			return nil, err
-		} else {
-			if response.Status != "OK" {
-				return nil, errors.New(response.StatusInfo)
-			} else {
-				return response, nil
-			}
-		}
-	}
+// TextRawContext is the context-aware variant of TextRaw.
+func (analyzer *Analyzer) TextRawContext(ctx context.Context, flavor, payload string, options url.Values) (*TextTitleResponse, error) {
+	return call(ctx, analyzer, textRawEndpoint, flavor, payload, options, nil)
 }
 
 // see Text
 func (analyzer *Analyzer) Title(flavor, payload string, options url.Values) (*TextTitleResponse, error) {
-	if !entryPoints.hasFlavor("title", flavor) {
-		return nil, errors.New(fmt.Sprintf("title info for %s not available", flavor))
-	}
-
-	options.Add(flavor, payload)
-	url := entryPoints.urlFor(analyzer.baseUrl, "title", flavor)
-	data, err := analyzer.analyze(url, options, nil)
+	return analyzer.TitleContext(context.Background(), flavor, payload, options)
+}
 
-	if err != nil {
-		return nil, err
-	} else {
-		response := new(TextTitleResponse)
-		err := json.Unmarshal(data, &response)
-		if err != nil {
-			return nil, err
-		} else {
-			if response.Status != "OK" {
-				return nil, errors.New(response.StatusInfo)
-			} else {
-				return response, nil
-			}
-		}
-	}
+// TitleContext is the context-aware variant of Title.
+func (analyzer *Analyzer) TitleContext(ctx context.Context, flavor, payload string, options url.Values) (*TextTitleResponse, error) {
+	return call(ctx, analyzer, titleEndpoint, flavor, payload, options, nil)
 }
 
 /*
@@ -562,6 +450,7 @@ func (analyzer *Analyzer) Title(flavor, payload string, options url.Values) (*Te
    INPUT:
    flavor -> which version of the call, i.e.  url or image.
    payload -> the data to analyze, the url or image path with depends on flavor
+   (for an image in memory or a stream, see FaceFromBytes and FaceFromReader)
    options -> various parameters that can be used to adjust how the API works, see below for more info on the available options.
 
    Available Options:
@@ -575,45 +464,20 @@ func (analyzer *Analyzer) Title(flavor, payload string, options url.Values) (*Te
    The response, already converted from JSON to a FaceResponse object.
 */
 func (analyzer *Analyzer) Face(flavor, payload string, options url.Values) (*FaceResponse, error) {
-	if !entryPoints.hasFlavor("face", flavor) {
-		return nil, errors.New(fmt.Sprintf("face info for %s not available", flavor))
-	}
-
-	var binData io.Reader
+	return analyzer.FaceContext(context.Background(), flavor, payload, options)
+}
 
-	switch flavor {
-	case "url":
-		options.Add(flavor, payload)
-		binData = nil
-	case "image":
-		imageData, err := ioutil.ReadFile(payload)
+// FaceContext is the context-aware variant of Face.
+func (analyzer *Analyzer) FaceContext(ctx context.Context, flavor, payload string, options url.Values) (*FaceResponse, error) {
+	if flavor == "image" {
+		imageData, err := analyzer.readImageFile(payload)
 		if err != nil {
 			return nil, err
 		}
-		binData = bytes.NewReader(imageData)
-		options.Set("imagePostMode", "raw")
-	default:
-		return nil, errors.New(fmt.Sprintf("face flavor for %s not support", flavor))
+		return analyzer.FaceFromBytes(ctx, imageData, options)
 	}
 
-	url := entryPoints.urlFor(analyzer.baseUrl, "face", flavor)
-	data, err := analyzer.analyze(url, options, binData)
-
-	if err != nil {
-		return nil, err
-	} else {
-		response := new(FaceResponse)
-		err := json.Unmarshal(data, &response)
-		if err != nil {
-			return nil, err
-		} else {
-			if response.Status != "OK" {
-				return nil, errors.New(response.StatusInfo)
-			} else {
-				return response, nil
-			}
-		}
-	}
+	return call(ctx, analyzer, faceEndpoint, flavor, payload, options, nil)
 }
 
 /*
@@ -632,30 +496,12 @@ func (analyzer *Analyzer) Face(flavor, payload string, options url.Values) (*Fac
    The response, already converted from JSON to a ImageExtractResponse object.
 */
 func (analyzer *Analyzer) ImageExtract(flavor, payload string, options url.Values) (*ImageExtractResponse, error) {
-	if !entryPoints.hasFlavor("image_extract", flavor) {
-		return nil, errors.New(fmt.Sprintf("image_extract info for %s not available", flavor))
-	}
-
-	options.Add(flavor, payload)
-	url := entryPoints.urlFor(analyzer.baseUrl, "image_extract", flavor)
-	data, err := analyzer.analyze(url, options, nil)
+	return analyzer.ImageExtractContext(context.Background(), flavor, payload, options)
+}
 
-	if err != nil {
-		return nil, err
-	} else {
-		fmt.Println(string(data))
-		response := new(ImageExtractResponse)
-		err := json.Unmarshal(data, &response)
-		if err != nil {
-			return nil, err
-		} else {
-			if response.Status != "OK" {
-				return nil, errors.New(response.StatusInfo)
-			} else {
-				return response, nil
-			}
-		}
-	}
+// ImageExtractContext is the context-aware variant of ImageExtract.
+func (analyzer *Analyzer) ImageExtractContext(ctx context.Context, flavor, payload string, options url.Values) (*ImageExtractResponse, error) {
+	return call(ctx, analyzer, imageExtractEndpoint, flavor, payload, options, nil)
 }
 
 /*
@@ -666,6 +512,7 @@ func (analyzer *Analyzer) ImageExtract(flavor, payload string, options url.Value
    INPUT:
    flavor -> which version of the call, i.e.  url or image.
    payload -> the data to analyze, the url or image path which depends on flavor
+   (for an image in memory or a stream, see ImageTagFromBytes and ImageTagFromReader)
    options -> various parameters that can be used to adjust how the API works, see below for more info on the available options.
 
    Available Options:
@@ -678,45 +525,20 @@ func (analyzer *Analyzer) ImageExtract(flavor, payload string, options url.Value
    The response, already converted from JSON to a ImageTagResponse object.
 */
 func (analyzer *Analyzer) ImageTag(flavor, payload string, options url.Values) (*ImageTagResponse, error) {
-	if !entryPoints.hasFlavor("image_tag", flavor) {
-		return nil, errors.New(fmt.Sprintf("image_tag info for %s not available", flavor))
-	}
-
-	var binData io.Reader
+	return analyzer.ImageTagContext(context.Background(), flavor, payload, options)
+}
 
-	switch flavor {
-	case "url":
-		options.Add(flavor, payload)
-		binData = nil
-	case "image":
-		imageData, err := ioutil.ReadFile(payload)
+// ImageTagContext is the context-aware variant of ImageTag.
+func (analyzer *Analyzer) ImageTagContext(ctx context.Context, flavor, payload string, options url.Values) (*ImageTagResponse, error) {
+	if flavor == "image" {
+		imageData, err := analyzer.readImageFile(payload)
 		if err != nil {
 			return nil, err
 		}
-		binData = bytes.NewReader(imageData)
-		options.Set("imagePostMode", "raw")
-	default:
-		return nil, errors.New(fmt.Sprintf("image_tag flavor for %s not support", flavor))
+		return analyzer.ImageTagFromBytes(ctx, imageData, options)
 	}
 
-	url := entryPoints.urlFor(analyzer.baseUrl, "image_tag", flavor)
-	data, err := analyzer.analyze(url, options, binData)
-
-	if err != nil {
-		return nil, err
-	} else {
-		response := new(ImageTagResponse)
-		err := json.Unmarshal(data, &response)
-		if err != nil {
-			return nil, err
-		} else {
-			if response.Status != "OK" {
-				return nil, errors.New(response.StatusInfo)
-			} else {
-				return response, nil
-			}
-		}
-	}
+	return call(ctx, analyzer, imageTagEndpoint, flavor, payload, options, nil)
 }
 
 /*
@@ -736,29 +558,12 @@ func (analyzer *Analyzer) ImageTag(flavor, payload string, options url.Values) (
    The response, already converted from JSON to a AuthorsResponse object.
 */
 func (analyzer *Analyzer) Authors(flavor, payload string, options url.Values) (*AuthorsResponse, error) {
-	if !entryPoints.hasFlavor("authors", flavor) {
-		return nil, errors.New(fmt.Sprintf("authors info for %s not available", flavor))
-	}
-
-	options.Add(flavor, payload)
-	url := entryPoints.urlFor(analyzer.baseUrl, "authors", flavor)
-	data, err := analyzer.analyze(url, options, nil)
+	return analyzer.AuthorsContext(context.Background(), flavor, payload, options)
+}
 
-	if err != nil {
-		return nil, err
-	} else {
-		response := new(AuthorsResponse)
-		err := json.Unmarshal(data, &response)
-		if err != nil {
-			return nil, err
-		} else {
-			if response.Status != "OK" {
-				return nil, errors.New(response.StatusInfo)
-			} else {
-				return response, nil
-			}
-		}
-	}
+// AuthorsContext is the context-aware variant of Authors.
+func (analyzer *Analyzer) AuthorsContext(ctx context.Context, flavor, payload string, options url.Values) (*AuthorsResponse, error) {
+	return call(ctx, analyzer, authorsEndpoint, flavor, payload, options, nil)
 }
 
 /*
@@ -778,29 +583,12 @@ func (analyzer *Analyzer) Authors(flavor, payload string, options url.Values) (*
    The response, already converted from JSON to a LanguageResponse object.
 */
 func (analyzer *Analyzer) Language(flavor, payload string, options url.Values) (*LanguageResponse, error) {
-	if !entryPoints.hasFlavor("language", flavor) {
-		return nil, errors.New(fmt.Sprintf("language analysis for %s not available", flavor))
-	}
-
-	options.Add(flavor, payload)
-	url := entryPoints.urlFor(analyzer.baseUrl, "language", flavor)
-	data, err := analyzer.analyze(url, options, nil)
+	return analyzer.LanguageContext(context.Background(), flavor, payload, options)
+}
 
-	if err != nil {
-		return nil, err
-	} else {
-		response := new(LanguageResponse)
-		err := json.Unmarshal(data, &response)
-		if err != nil {
-			return nil, err
-		} else {
-			if response.Status != "OK" {
-				return nil, errors.New(response.StatusInfo)
-			} else {
-				return response, nil
-			}
-		}
-	}
+// LanguageContext is the context-aware variant of Language.
+func (analyzer *Analyzer) LanguageContext(ctx context.Context, flavor, payload string, options url.Values) (*LanguageResponse, error) {
+	return call(ctx, analyzer, languageEndpoint, flavor, payload, options, nil)
 }
 
 /*
@@ -821,33 +609,12 @@ func (analyzer *Analyzer) Language(flavor, payload string, options url.Values) (
    The response, already converted from JSON to a FeedsResponse object.
 */
 func (analyzer *Analyzer) Feeds(flavor, payload, urlParam string, options url.Values) (*FeedsResponse, error) {
-	if !entryPoints.hasFlavor("feeds", flavor) {
-		return nil, errors.New(fmt.Sprintf("feeds info for %s not available", flavor))
-	}
-
-	options.Add(flavor, payload)
-	if flavor == "html" {
-		options.Add("url", urlParam)
-	}
-
-	url := entryPoints.urlFor(analyzer.baseUrl, "feeds", flavor)
-	data, err := analyzer.analyze(url, options, nil)
+	return analyzer.FeedsContext(context.Background(), flavor, payload, urlParam, options)
+}
 
-	if err != nil {
-		return nil, err
-	} else {
-		response := new(FeedsResponse)
-		err := json.Unmarshal(data, &response)
-		if err != nil {
-			return nil, err
-		} else {
-			if response.Status != "OK" {
-				return nil, errors.New(response.StatusInfo)
-			} else {
-				return response, nil
-			}
-		}
-	}
+// FeedsContext is the context-aware variant of Feeds.
+func (analyzer *Analyzer) FeedsContext(ctx context.Context, flavor, payload, urlParam string, options url.Values) (*FeedsResponse, error) {
+	return call(ctx, analyzer, feedsEndpoint, flavor, payload, options, url.Values{"url": {urlParam}})
 }
 
 /*
@@ -868,33 +635,12 @@ func (analyzer *Analyzer) Feeds(flavor, payload, urlParam string, options url.Va
    The response, already converted from JSON to a MicroFormatsResponse object.
 */
 func (analyzer *Analyzer) Microformats(flavor, payload, urlParam string, options url.Values) (*MicroFormatsResponse, error) {
-	if !entryPoints.hasFlavor("microformats", flavor) {
-		return nil, errors.New(fmt.Sprintf("microformats info for %s not available", flavor))
-	}
-
-	options.Add(flavor, payload)
-	if flavor == "html" {
-		options.Add("url", urlParam)
-	}
-
-	url := entryPoints.urlFor(analyzer.baseUrl, "microformats", flavor)
-	data, err := analyzer.analyze(url, options, nil)
+	return analyzer.MicroformatsContext(context.Background(), flavor, payload, urlParam, options)
+}
 
-	if err != nil {
-		return nil, err
-	} else {
-		response := new(MicroFormatsResponse)
-		err := json.Unmarshal(data, &response)
-		if err != nil {
-			return nil, err
-		} else {
-			if response.Status != "OK" {
-				return nil, errors.New(response.StatusInfo)
-			} else {
-				return response, nil
-			}
-		}
-	}
+// MicroformatsContext is the context-aware variant of Microformats.
+func (analyzer *Analyzer) MicroformatsContext(ctx context.Context, flavor, payload, urlParam string, options url.Values) (*MicroFormatsResponse, error) {
+	return call(ctx, analyzer, microformatsEndpoint, flavor, payload, options, url.Values{"url": {urlParam}})
 }
 
 /*
@@ -922,29 +668,12 @@ func (analyzer *Analyzer) Microformats(flavor, payload, urlParam string, options
    The response, already converted from JSON to a CombinedResponse object.
 */
 func (analyzer *Analyzer) Combined(flavor, payload string, options url.Values) (*CombinedResponse, error) {
-	if !entryPoints.hasFlavor("combined", flavor) {
-		return nil, errors.New(fmt.Sprintf("combined analysis for %s not available", flavor))
-	}
-
-	options.Add(flavor, payload)
-	url := entryPoints.urlFor(analyzer.baseUrl, "combined", flavor)
-	data, err := analyzer.analyze(url, options, nil)
+	return analyzer.CombinedContext(context.Background(), flavor, payload, options)
+}
 
-	if err != nil {
-		return nil, err
-	} else {
-		response := new(CombinedResponse)
-		err := json.Unmarshal(data, &response)
-		if err != nil {
-			return nil, err
-		} else {
-			if response.Status != "OK" {
-				return nil, errors.New(response.StatusInfo)
-			} else {
-				return response, nil
-			}
-		}
-	}
+// CombinedContext is the context-aware variant of Combined.
+func (analyzer *Analyzer) CombinedContext(ctx context.Context, flavor, payload string, options url.Values) (*CombinedResponse, error) {
+	return call(ctx, analyzer, combinedEndpoint, flavor, payload, options, nil)
 }
 
 /*
@@ -964,66 +693,164 @@ func (analyzer *Analyzer) Combined(flavor, payload string, options url.Values) (
    The response, already converted from JSON to a PublicationDateResponse object.
 */
 func (analyzer *Analyzer) PublicationDate(flavor, payload string, options url.Values) (*PublicationDateResponse, error) {
-	if !entryPoints.hasFlavor("publication_date", flavor) {
-		return nil, errors.New(fmt.Sprintf("publication_date info for %s not available", flavor))
+	return analyzer.PublicationDateContext(context.Background(), flavor, payload, options)
+}
+
+// PublicationDateContext is the context-aware variant of PublicationDate.
+func (analyzer *Analyzer) PublicationDateContext(ctx context.Context, flavor, payload string, options url.Values) (*PublicationDateResponse, error) {
+	return call(ctx, analyzer, publicationDateEndpoint, flavor, payload, options, nil)
+}
+
+// Send request, the response status is checked and anything but OK
+// is turned into an *APIError. Failed attempts are re-sent according to
+// the retry policy, the form body or image bytes are reused as is. The
+// interceptors, if any, run first, see Interceptor.
+func (analyzer *Analyzer) invoke(ctx context.Context, req *request) (data []byte, err error) {
+	arrange, flavor := req.arrange, req.flavor
+	info := newCallInfo(req)
+	started := make([]context.Context, len(analyzer.observers))
+	for i, observer := range analyzer.observers {
+		if starter, ok := observer.(CallStarter); ok {
+			ctx = starter.StartCall(ctx, info)
+			started[i] = ctx
+		}
+	}
+
+	start := time.Now()
+	defer func() {
+		info.Latency = time.Since(start)
+		info.Err = err
+		analyzer.logCall(ctx, info)
+		for i, observer := range analyzer.observers {
+			if started[i] != nil {
+				observer.ObserveCall(started[i], info)
+			} else {
+				observer.ObserveCall(ctx, info)
+			}
+		}
+	}()
+
+	if analyzer.strictOptions {
+		if err := ValidateOptions(arrange, req.values); err != nil {
+			return nil, err
+		}
 	}
 
-	options.Add(flavor, payload)
-	url := entryPoints.urlFor(analyzer.baseUrl, "publication_date", flavor)
-	data, err := analyzer.analyze(url, options, nil)
+	var key string
+	if analyzer.cache != nil {
+		key = cacheKey(arrange, flavor, req.values, req.binData)
+		if data, ok := analyzer.cache.Get(key); ok {
+			atomic.AddInt64(&analyzer.cacheCounters.hits, 1)
+			info.CacheHit = true
+			info.setResponse(data)
+			return data, nil
+		}
+		atomic.AddInt64(&analyzer.cacheCounters.misses, 1)
+	}
 
+	if req.apiKey == "" && analyzer.keyPool != nil {
+		data, err = analyzer.keyPool.do(func(apiKey string) ([]byte, int64, error) {
+			data, err := analyzer.sendWithRetry(ctx, info, req, apiKey)
+			return data, info.Transactions, err
+		})
+	} else {
+		var apiKey string
+		if apiKey, err = analyzer.resolveAPIKey(ctx, req); err != nil {
+			return nil, err
+		}
+		data, err = analyzer.sendWithRetry(ctx, info, req, apiKey)
+	}
 	if err != nil {
 		return nil, err
-	} else {
-		response := new(PublicationDateResponse)
-		err := json.Unmarshal(data, &response)
+	}
+	if analyzer.cache != nil {
+		analyzer.cache.Set(key, data)
+	}
+	return data, nil
+}
+
+// Sends the request with the given key, retrying according to the policy
+func (analyzer *Analyzer) sendWithRetry(ctx context.Context, info *CallInfo, req *request, apiKey string) ([]byte, error) {
+	url, body := req.encode(analyzer.baseUrl, apiKey)
+
+	if err := analyzer.ledger.check(); err != nil {
+		return nil, err
+	}
+
+	for attempt := 1; ; attempt++ {
+		release, err := analyzer.limiter.acquire(ctx)
 		if err != nil {
-			return nil, err
-		} else {
-			if response.Status != "OK" {
-				return nil, errors.New(response.StatusInfo)
-			} else {
-				return response, nil
+			if attempt > 1 {
+				return nil, &RetryError{Attempts: attempt - 1, Err: err}
 			}
+			return nil, err
+		}
+		info.Attempts = attempt
+		data, err := analyzer.send(ctx, info, url, body, req.header)
+		release()
+		if err == nil {
+			return data, nil
+		}
+
+		policy := analyzer.retry
+		if !policy.enabled() {
+			return nil, err
+		}
+		if attempt >= policy.MaxAttempts || !policy.retryable(err) {
+			return nil, &RetryError{Attempts: attempt, Err: err}
+		}
+		if err := sleepContext(ctx, policy.delay(attempt+1)); err != nil {
+			return nil, &RetryError{Attempts: attempt, Err: err}
 		}
 	}
 }
 
-// Send request
-func (analyzer *Analyzer) analyze(url string, payload url.Values, binData io.Reader) ([]byte, error) {
-	client := http.Client{}
-	payload.Add("apikey", analyzer.apiKey)
-	payload.Add("outputMode", "json")
-	var req *http.Request
-
-	if binData == nil {
-		req, _ = http.NewRequest("POST", url, strings.NewReader(payload.Encode()))
-	} else {
-		url += fmt.Sprintf("?%s", payload.Encode())
-		req, _ = http.NewRequest("POST", url, binData)
+// A single attempt, the outcome is noted in info
+func (analyzer *Analyzer) send(ctx context.Context, info *CallInfo, url string, body []byte, header http.Header) ([]byte, error) {
+	arrange, flavor := info.Endpoint, info.Flavor
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+	if err != nil {
+		return nil, redactURLError(err)
+	}
+	for k, v := range header {
+		req.Header[k] = append([]string(nil), v...)
 	}
 	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
 	req.Header.Add("Accept-Encoding", "gzip")
+	if analyzer.userAgent != "" {
+		req.Header.Set("User-Agent", analyzer.userAgent)
+	}
 
-	resp, err := client.Do(req)
+	resp, err := analyzer.client.Do(req)
+	if err != nil {
+		return nil, redactURLError(err)
+	}
+	info.StatusCode = resp.StatusCode
+
+	data, err := analyzer.readBody(resp)
 	if err != nil {
 		return nil, err
-	} else {
-		var data []byte
-		var err error
-
-		switch resp.Header.Get("Content-Encoding") {
-		case "gzip":
-			reader, _ := gzip.NewReader(resp.Body)
-			defer reader.Close()
-			data, err = ioutil.ReadAll(reader)
-		default:
-			data, err = ioutil.ReadAll(resp.Body)
-		}
+	}
 
-		if err != nil {
-			return nil, err
-		}
-		return data, nil
+	ok := resp.StatusCode >= 200 && resp.StatusCode < 300
+	status, err := info.setResponse(data)
+	if !jsonContentType(resp.Header.Get("Content-Type")) {
+		return nil, newTransportError(resp, data, ErrNotJSON)
+	} else if err != nil && ok {
+		return nil, newTransportError(resp, data, ErrNotJSON)
+	} else if err != nil {
+		return nil, newTransportError(resp, data, nil)
+	}
+	if !ok && status.Status == "" {
+		// e.g. the JSON error of a gateway, not an AlchemyAPI answer
+		return nil, newTransportError(resp, data, nil)
+	}
+	if status.Status != "OK" {
+		return nil, newAPIError(arrange, flavor, resp.StatusCode, status.StatusInfo)
+	}
+	if !ok {
+		return nil, newTransportError(resp, data, nil)
 	}
+	analyzer.ledger.record(arrange, flavor, info.Transactions)
+	return data, nil
 }
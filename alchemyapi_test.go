@@ -1,11 +1,14 @@
 package alchemyapi
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"testing"
+	"time"
 )
 
 func TestNewAnalyzer(t *testing.T) {
@@ -107,3 +110,117 @@ func TestAnalyzerSentiment(t *testing.T) {
 		}
 	}
 }
+
+func TestAnalyzerContextCancel(t *testing.T) {
+	apiKey := "foooooooooooooooooooooooooooooooooooobar"
+	release := make(chan struct{})
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		// never responds, waits for the client to go away
+		r.ParseForm()
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	}
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+	defer close(release)
+
+	analyzer, _ := NewAnalyzer(apiKey)
+	analyzer.SetBaseUrl(server.URL)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := analyzer.SentimentContext(ctx, "text", "foobar", url.Values{})
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Fatal("should raise exception")
+		}
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("want %v, but %v", context.Canceled, err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("cancelled call should not hang")
+	}
+}
+
+func TestAnalyzerContextDeadline(t *testing.T) {
+	apiKey := "foooooooooooooooooooooooooooooooooooobar"
+	release := make(chan struct{})
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	}
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+	defer close(release)
+
+	analyzer, _ := NewAnalyzer(apiKey)
+	analyzer.SetBaseUrl(server.URL)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := analyzer.CombinedContext(ctx, "url", "http://example.com", url.Values{})
+	if err == nil {
+		t.Fatal("should raise exception")
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("want %v, but %v", context.DeadlineExceeded, err)
+	}
+}
+
+func TestNewAnalyzerOptions(t *testing.T) {
+	apiKey := "foooooooooooooooooooooooooooooooooooobar"
+	var gotAgent string
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		gotAgent = r.UserAgent()
+		w.Write([]byte("{\"status\":\"OK\",\"language\":\"english\"}"))
+	}
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	client := &http.Client{Timeout: time.Second}
+	analyzer, err := NewAnalyzer(apiKey,
+		WithHTTPClient(client),
+		WithBaseURL(server.URL+"/"),
+		WithUserAgent("pipeline/1.0"),
+	)
+	if err != nil {
+		t.Fatal("should not be error")
+	}
+
+	if analyzer.client != client {
+		t.Error("client should be setted")
+	}
+
+	if analyzer.baseUrl != server.URL {
+		t.Errorf("want %s, but %s", server.URL, analyzer.baseUrl)
+	}
+
+	if _, err := analyzer.Language("text", "foobar", url.Values{}); err != nil {
+		t.Errorf("should not raise exception, %v", err)
+	}
+
+	if gotAgent != "pipeline/1.0" {
+		t.Errorf("want %s, but %s", "pipeline/1.0", gotAgent)
+	}
+
+	analyzer, _ = NewAnalyzer(apiKey)
+	if analyzer.baseUrl != DefaultBaseURL {
+		t.Errorf("want %s, but %s", DefaultBaseURL, analyzer.baseUrl)
+	}
+	if analyzer.userAgent != DefaultUserAgent {
+		t.Errorf("want %s, but %s", DefaultUserAgent, analyzer.userAgent)
+	}
+}
@@ -0,0 +1,164 @@
+/*
+	   Package alchemyotel traces Analyzer calls with OpenTelemetry.
+
+		analyzer, err := alchemyapi.NewAnalyzer(key,
+			alchemyapi.WithObserver(alchemyotel.NewTracer()),
+			alchemyapi.WithTransport(alchemyotel.Transport(nil)),
+		)
+
+	   Every Analyzer method gets a span, a child of the span in the context
+	   passed to the method. Every request it sends to AlchemyAPI, retries
+	   included, gets a child span of it: one for most methods, one per chunk
+	   of a chunked text. Transport propagates the span of the request to
+	   AlchemyAPI in the request headers. Without a configured tracer
+	   provider nothing is recorded.
+*/
+package alchemyotel
+
+/**
+  Copyright 2015 AlchemyAPI
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+      http://www.apache.org/licenses/LICENSE-2.0
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+import (
+	"context"
+	"net/http"
+
+	alchemyapi "github.com/elvuel/alchemyapi_go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const InstrumentationName = "github.com/elvuel/alchemyapi_go/alchemyotel"
+
+// Tracer is an alchemyapi.MethodObserver creating a span per method and
+// a child span per request of the method.
+type Tracer struct {
+	provider trace.TracerProvider
+}
+
+var (
+	_ alchemyapi.MethodObserver = (*Tracer)(nil)
+	_ alchemyapi.CallStarter    = (*Tracer)(nil)
+)
+
+type Option func(*Tracer)
+
+// Uses the given provider instead of the global one.
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(tracer *Tracer) {
+		tracer.provider = provider
+	}
+}
+
+// Creates new Tracer, by default using the global tracer provider.
+func NewTracer(opts ...Option) *Tracer {
+	tracer := &Tracer{}
+	for _, opt := range opts {
+		opt(tracer)
+	}
+	return tracer
+}
+
+func (tracer *Tracer) tracer() trace.Tracer {
+	provider := tracer.provider
+	if provider == nil {
+		provider = otel.GetTracerProvider()
+	}
+	return provider.Tracer(InstrumentationName, trace.WithInstrumentationVersion(alchemyapi.Version))
+}
+
+func (tracer *Tracer) StartMethod(ctx context.Context, info *alchemyapi.CallInfo) context.Context {
+	return tracer.start(ctx, "alchemyapi."+info.Endpoint, trace.SpanKindInternal, info)
+}
+
+func (tracer *Tracer) EndMethod(ctx context.Context, info *alchemyapi.CallInfo) {
+	span := trace.SpanFromContext(ctx)
+	if !span.IsRecording() {
+		return
+	}
+
+	recordError(span, info.Err)
+	span.End()
+}
+
+func (tracer *Tracer) StartCall(ctx context.Context, info *alchemyapi.CallInfo) context.Context {
+	return tracer.start(ctx, "alchemyapi."+info.Endpoint+".request", trace.SpanKindClient, info)
+}
+
+func (tracer *Tracer) ObserveCall(ctx context.Context, info *alchemyapi.CallInfo) {
+	span := trace.SpanFromContext(ctx)
+	if !span.IsRecording() {
+		return
+	}
+
+	span.SetAttributes(
+		attribute.String("alchemyapi.status", info.Status),
+		attribute.String("alchemyapi.language", info.Language),
+		attribute.Int64("alchemyapi.transactions", info.Transactions),
+		attribute.Int("alchemyapi.attempts", info.Attempts),
+		attribute.Bool("alchemyapi.cache_hit", info.CacheHit),
+		attribute.Int("alchemyapi.response_size", info.ResponseSize),
+	)
+	if info.StatusCode != 0 {
+		span.SetAttributes(attribute.Int("http.status_code", info.StatusCode))
+	}
+	if info.StatusInfo != "" {
+		span.SetAttributes(attribute.String("alchemyapi.status_info", info.StatusInfo))
+	}
+	recordError(span, info.Err)
+	span.End()
+}
+
+func (tracer *Tracer) start(ctx context.Context, name string, kind trace.SpanKind, info *alchemyapi.CallInfo) context.Context {
+	ctx, _ = tracer.tracer().Start(ctx, name,
+		trace.WithSpanKind(kind),
+		trace.WithAttributes(
+			attribute.String("alchemyapi.endpoint", info.Endpoint),
+			attribute.String("alchemyapi.flavor", info.Flavor),
+			attribute.String("alchemyapi.path", info.Path),
+			attribute.Int("alchemyapi.payload_length", info.PayloadSize),
+		),
+	)
+	return ctx
+}
+
+func recordError(span trace.Span, err error) {
+	if err == nil {
+		return
+	}
+	span.RecordError(err)
+	span.SetAttributes(attribute.String("alchemyapi.error_class", alchemyapi.ErrorClass(err)))
+	span.SetStatus(codes.Error, err.Error())
+}
+
+// Transport injects the span of the request context into the request
+// headers using the global propagator, then sends the request with base
+// (http.DefaultTransport if nil).
+func Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &transport{base: base}
+}
+
+type transport struct {
+	base http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	otel.GetTextMapPropagator().Inject(req.Context(), propagation.HeaderCarrier(req.Header))
+	return t.base.RoundTrip(req)
+}
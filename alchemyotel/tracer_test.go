@@ -0,0 +1,131 @@
+package alchemyotel
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	alchemyapi "github.com/elvuel/alchemyapi_go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTracer(t *testing.T) {
+	var traceparent string
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+		r.ParseForm()
+		switch r.FormValue("text") {
+		case "bad":
+			w.Write([]byte("{\"status\":\"ERROR\",\"statusInfo\":\"unsupported-text-language\"}"))
+		default:
+			w.Write([]byte("{\"status\":\"OK\",\"language\":\"english\",\"totalTransactions\":\"1\"}"))
+		}
+	}
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	analyzer, _ := alchemyapi.NewAnalyzer(strings.Repeat("0", 40),
+		alchemyapi.WithBaseURL(server.URL),
+		alchemyapi.WithObserver(NewTracer(WithTracerProvider(provider))),
+		alchemyapi.WithTransport(Transport(nil)),
+	)
+
+	ctx, parent := provider.Tracer("test").Start(context.Background(), "ingest")
+	analyzer.SentimentContext(ctx, "text", "foobar", url.Values{})
+	analyzer.SentimentContext(ctx, "text", "bad", url.Values{})
+	parent.End()
+
+	spans := recorder.Ended()
+	if len(spans) != 5 {
+		t.Fatalf("want %d spans, but %d", 5, len(spans))
+	}
+
+	request, method := spans[0], spans[1]
+	if method.Name() != "alchemyapi.sentiment" || method.Parent().SpanID() != parent.SpanContext().SpanID() {
+		t.Errorf("unexpected span %s", method.Name())
+	}
+	if request.Name() != "alchemyapi.sentiment.request" || request.Parent().SpanID() != method.SpanContext().SpanID() {
+		t.Errorf("unexpected span %s", request.Name())
+	}
+	attrs := map[attribute.Key]attribute.Value{}
+	for _, kv := range request.Attributes() {
+		attrs[kv.Key] = kv.Value
+	}
+	if attrs["alchemyapi.language"].AsString() != "english" || attrs["alchemyapi.transactions"].AsInt64() != 1 ||
+		attrs["alchemyapi.payload_length"].AsInt64() != 6 || attrs["alchemyapi.flavor"].AsString() != "text" {
+		t.Errorf("unexpected attributes %v", request.Attributes())
+	}
+	if !strings.Contains(traceparent, request.SpanContext().TraceID().String()) {
+		t.Errorf("the span should be propagated, but %s", traceparent)
+	}
+
+	if failed := spans[2]; failed.Status().Code != codes.Error {
+		t.Errorf("want %v, but %v", codes.Error, failed.Status().Code)
+	}
+	if failed := spans[3]; failed.Name() != "alchemyapi.sentiment" || failed.Status().Code != codes.Error {
+		t.Errorf("want %v, but %v", codes.Error, failed.Status().Code)
+	}
+}
+
+func TestTracerChunking(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("{\"status\":\"OK\",\"docSentiment\":{\"type\":\"neutral\"}}"))
+	}
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	analyzer, _ := alchemyapi.NewAnalyzer(strings.Repeat("0", 40),
+		alchemyapi.WithBaseURL(server.URL),
+		alchemyapi.WithObserver(NewTracer(WithTracerProvider(provider))),
+		alchemyapi.WithChunking(alchemyapi.ChunkOptions{MaxBytes: 40}),
+	)
+	text := "good news and more good news, really.\n\nbad news and even more bad news, sadly."
+	if _, err := analyzer.Sentiment("text", text, url.Values{}); err != nil {
+		t.Fatal(err)
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 3 {
+		t.Fatalf("want %d spans, but %d", 3, len(spans))
+	}
+	method := spans[2]
+	if method.Name() != "alchemyapi.sentiment" {
+		t.Errorf("want %v, but %v", "alchemyapi.sentiment", method.Name())
+	}
+	for _, request := range spans[:2] {
+		if request.Name() != "alchemyapi.sentiment.request" || request.Parent().SpanID() != method.SpanContext().SpanID() {
+			t.Errorf("every chunk should be a child of the method, but %s", request.Name())
+		}
+	}
+}
+
+func TestTracerNoop(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("{\"status\":\"OK\"}"))
+	}
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	analyzer, _ := alchemyapi.NewAnalyzer(strings.Repeat("0", 40),
+		alchemyapi.WithBaseURL(server.URL),
+		alchemyapi.WithObserver(NewTracer()),
+	)
+	if _, err := analyzer.Language("text", "foobar", url.Values{}); err != nil {
+		t.Errorf("should not raise exception, %v", err)
+	}
+}
@@ -0,0 +1,262 @@
+/*
+   Package alchemytest provides a fake AlchemyAPI server for testing code
+   built on the alchemyapi package.
+
+	server := alchemytest.NewServer()
+	defer server.Close()
+
+	server.Respond("entities", &alchemyapi.EntitiesResponse{Status: "OK", Language: "english"})
+	server.Fail("sentiment", "unsupported-text-language")
+
+	analyzer := server.Analyzer()
+	...
+	for _, req := range server.Requests() { ... }
+*/
+package alchemytest
+
+/**
+  Copyright 2015 AlchemyAPI
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+      http://www.apache.org/licenses/LICENSE-2.0
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	alchemyapi "github.com/elvuel/alchemyapi_go"
+)
+
+// A valid looking api key, accepted by NewAnalyzer
+var APIKey = strings.Repeat("0", 40)
+
+// Request is a request received by the Server.
+type Request struct {
+	Endpoint string     // the entry point arrange, e.g. "entities"
+	Flavor   string     // url, text, html or image
+	Path     string     // e.g. /text/TextGetRankedNamedEntities
+	Form     url.Values // query and form values, apikey included
+	Body     []byte     // raw body, the image for the image flavor
+	Header   http.Header
+}
+
+// Responder builds the response of a request. A []byte or string is
+// written as is, anything else is encoded as JSON.
+type Responder func(req *Request) interface{}
+
+// Server is a fake AlchemyAPI serving every route of the entry points.
+// Unless told otherwise every endpoint answers with status OK.
+type Server struct {
+	*httptest.Server
+
+	mu         sync.Mutex
+	routes     map[string][2]string // path -> arrange, flavor
+	responders map[string]Responder // by arrange
+	gzip       bool
+	latency    time.Duration
+	quota      int // remaining successful calls, -1 means unlimited
+	requests   []Request
+}
+
+// Creates and starts new Server, Close it when done.
+func NewServer() *Server {
+	server := &Server{
+		routes:     make(map[string][2]string),
+		responders: make(map[string]Responder),
+		quota:      -1,
+	}
+	for arrange, flavors := range alchemyapi.GetEntryPoints() {
+		for flavor, uri := range flavors {
+			server.routes[uri] = [2]string{arrange, flavor}
+		}
+	}
+	server.Server = httptest.NewServer(http.HandlerFunc(server.serve))
+	return server
+}
+
+// Creates new Analyzer talking to the server.
+func (server *Server) Analyzer(opts ...alchemyapi.Option) *alchemyapi.Analyzer {
+	opts = append([]alchemyapi.Option{alchemyapi.WithBaseURL(server.URL)}, opts...)
+	analyzer, err := alchemyapi.NewAnalyzer(APIKey, opts...)
+	if err != nil {
+		panic(err)
+	}
+	return analyzer
+}
+
+// Answers every call of the endpoint with the same response.
+func (server *Server) Respond(endpoint string, response interface{}) {
+	server.RespondFunc(endpoint, func(*Request) interface{} {
+		return response
+	})
+}
+
+// Answers every call of the endpoint with the responder's result.
+func (server *Server) RespondFunc(endpoint string, responder Responder) {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	server.responders[endpoint] = responder
+}
+
+// Answers every call of the endpoint with an error status.
+func (server *Server) Fail(endpoint, statusInfo string) {
+	server.Respond(endpoint, errorResponse(statusInfo))
+}
+
+// Compresses the responses with gzip.
+func (server *Server) SetGzip(enabled bool) {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	server.gzip = enabled
+}
+
+// Delays every response.
+func (server *Server) SetLatency(latency time.Duration) {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	server.latency = latency
+}
+
+// Allows n more calls, the following ones are answered with
+// daily-transaction-limit-exceeded. A negative n means unlimited.
+func (server *Server) SetQuota(n int) {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	server.quota = n
+}
+
+// Returns the requests received so far, in order.
+func (server *Server) Requests() []Request {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	return append([]Request(nil), server.requests...)
+}
+
+// Forgets the received requests and every configured behavior.
+func (server *Server) Reset() {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	server.responders = make(map[string]Responder)
+	server.requests = nil
+	server.gzip = false
+	server.latency = 0
+	server.quota = -1
+}
+
+func (server *Server) serve(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+	form, _ := url.ParseQuery(r.URL.RawQuery)
+	if r.URL.Query().Get("imagePostMode") != "raw" {
+		if values, err := url.ParseQuery(string(body)); err == nil {
+			for k, v := range values {
+				form[k] = append(form[k], v...)
+			}
+		}
+	}
+
+	route, known := server.routes[r.URL.Path]
+	req := &Request{
+		Endpoint: route[0],
+		Flavor:   route[1],
+		Path:     r.URL.Path,
+		Form:     form,
+		Body:     body,
+		Header:   r.Header.Clone(),
+	}
+
+	server.mu.Lock()
+	server.requests = append(server.requests, *req)
+	latency := server.latency
+	compress := server.gzip && strings.Contains(r.Header.Get("Accept-Encoding"), "gzip")
+	responder := server.responders[req.Endpoint]
+	var response interface{}
+	switch {
+	case !known:
+		response = errorResponse("unsupported-endpoint")
+	case len(form.Get("apikey")) != 40:
+		response = errorResponse("invalid-api-key")
+	case server.quota == 0:
+		response = errorResponse("daily-transaction-limit-exceeded")
+	default:
+		if server.quota > 0 {
+			server.quota--
+		}
+	}
+	server.mu.Unlock()
+
+	if latency > 0 {
+		select {
+		case <-time.After(latency):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	if response == nil {
+		if responder != nil {
+			response = responder(req)
+		} else {
+			response = defaultResponse(req)
+		}
+	}
+
+	var data []byte
+	switch v := response.(type) {
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		data, _ = json.Marshal(v)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if compress {
+		w.Header().Set("Content-Encoding", "gzip")
+	}
+	if !known {
+		w.WriteHeader(http.StatusNotFound)
+	}
+	if compress {
+		writer := gzip.NewWriter(w)
+		writer.Write(data)
+		writer.Close()
+		return
+	}
+	w.Write(data)
+}
+
+func errorResponse(statusInfo string) map[string]string {
+	return map[string]string{"status": "ERROR", "statusInfo": statusInfo}
+}
+
+// Echoes the payload back like AlchemyAPI does
+func defaultResponse(req *Request) map[string]string {
+	response := map[string]string{
+		"status":            "OK",
+		"usage":             "By accessing AlchemyAPI or using information generated by AlchemyAPI, you are agreeing to be bound by the AlchemyAPI Terms of Use: http://www.alchemyapi.com/company/terms.html",
+		"language":          "english",
+		"totalTransactions": "1",
+	}
+	switch req.Flavor {
+	case "url":
+		response["url"] = req.Form.Get("url")
+	case "text":
+		response["text"] = req.Form.Get("text")
+	}
+	return response
+}
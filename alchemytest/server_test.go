@@ -0,0 +1,136 @@
+package alchemytest
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	alchemyapi "github.com/elvuel/alchemyapi_go"
+)
+
+func TestServerDefaultResponse(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+
+	analyzer := server.Analyzer()
+	for arrange, flavors := range alchemyapi.GetEntryPoints() {
+		for flavor := range flavors {
+			if flavor == "image" {
+				continue
+			}
+			results, err := analyzer.Batch(context.Background(), arrange,
+				[]alchemyapi.BatchItem{{Flavor: flavor, Payload: "foobar", Target: "foo"}},
+				alchemyapi.BatchOptions{})
+			if err != nil {
+				// not every arrange is a batch endpoint
+				continue
+			}
+			if results[0].Err != nil {
+				t.Errorf("%s/%s should not raise exception, %v", arrange, flavor, results[0].Err)
+			}
+		}
+	}
+
+	resp, err := analyzer.Sentiment("text", "foobar", url.Values{})
+	if err != nil {
+		t.Fatalf("should not raise exception, %v", err)
+	}
+	if resp.Text != "foobar" {
+		t.Errorf("want %s, but %s", "foobar", resp.Text)
+	}
+}
+
+func TestServerRespondAndRecord(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+
+	server.Respond("entities", &alchemyapi.EntitiesResponse{
+		Status:   "OK",
+		Language: "english",
+		Entities: []alchemyapi.Entity{{Text: "Denver", Type: "City"}},
+	})
+	server.SetGzip(true)
+
+	analyzer := server.Analyzer()
+	options := url.Values{"sentiment": {"1"}}
+	resp, err := analyzer.Entities("text", "Bob lives in Denver", options)
+	if err != nil {
+		t.Fatalf("should not raise exception, %v", err)
+	}
+	if len(resp.Entities) != 1 || resp.Entities[0].Text != "Denver" {
+		t.Errorf("unexpected entities %+v", resp.Entities)
+	}
+
+	requests := server.Requests()
+	if len(requests) != 1 {
+		t.Fatalf("want %d, but %d", 1, len(requests))
+	}
+	req := requests[0]
+	if req.Endpoint != "entities" || req.Flavor != "text" {
+		t.Errorf("want entities/text, but %s/%s", req.Endpoint, req.Flavor)
+	}
+	if req.Form.Get("text") != "Bob lives in Denver" || req.Form.Get("sentiment") != "1" {
+		t.Errorf("unexpected form %v", req.Form)
+	}
+}
+
+func TestServerImage(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+
+	image := filepath.Join(t.TempDir(), "face.jpg")
+	os.WriteFile(image, []byte("not really a jpeg"), 0644)
+
+	if _, err := server.Analyzer().Face("image", image, url.Values{}); err != nil {
+		t.Fatalf("should not raise exception, %v", err)
+	}
+
+	req := server.Requests()[0]
+	if string(req.Body) != "not really a jpeg" || req.Form.Get("imagePostMode") != "raw" {
+		t.Errorf("unexpected request %+v", req)
+	}
+}
+
+func TestServerFailAndQuota(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+
+	analyzer := server.Analyzer()
+	server.Fail("sentiment", "unsupported-text-language")
+	if _, err := analyzer.Sentiment("text", "foobar", url.Values{}); !errors.Is(err, alchemyapi.ErrUnsupportedTextLanguage) {
+		t.Errorf("want %v, but %v", alchemyapi.ErrUnsupportedTextLanguage, err)
+	}
+
+	server.SetQuota(1)
+	if _, err := analyzer.Keywords("text", "foobar", url.Values{}); err != nil {
+		t.Errorf("should not raise exception, %v", err)
+	}
+	if _, err := analyzer.Keywords("text", "foobar", url.Values{}); !errors.Is(err, alchemyapi.ErrDailyLimitExceeded) {
+		t.Errorf("want %v, but %v", alchemyapi.ErrDailyLimitExceeded, err)
+	}
+
+	server.Reset()
+	if _, err := analyzer.Sentiment("text", "foobar", url.Values{}); err != nil {
+		t.Errorf("should not raise exception, %v", err)
+	}
+	if len(server.Requests()) != 1 {
+		t.Errorf("want %d, but %d", 1, len(server.Requests()))
+	}
+}
+
+func TestServerLatency(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+
+	server.SetLatency(time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	if _, err := server.Analyzer().LanguageContext(ctx, "text", "foobar", url.Values{}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("want %v, but %v", context.DeadlineExceeded, err)
+	}
+}
@@ -0,0 +1,227 @@
+/*
+	   Package annotate renders the faces found by Analyzer.Face onto the
+	   original image, for reviewing results by eye.
+
+		response, err := analyzer.FaceFromBytes(ctx, original, url.Values{})
+		img, _, err := image.Decode(bytes.NewReader(original))
+		err = annotate.Render(w, img, response, nil)
+
+	   Every face gets a rectangle and a label with the age range, gender and
+	   identity along with their scores. Crops and WriteCrops cut out the
+	   single faces. The face positions must refer to img as decoded, which is
+	   the case unless the original carries an EXIF orientation; with
+	   alchemyapi.WithImagePreprocessing they refer to the upright image.
+*/
+package annotate
+
+/**
+  Copyright 2015 AlchemyAPI
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+      http://www.apache.org/licenses/LICENSE-2.0
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	alchemyapi "github.com/elvuel/alchemyapi_go"
+)
+
+// Options tunes the rendering, the zero value (or nil) picks defaults.
+type Options struct {
+	Color     color.Color // rectangles and label background, red by default
+	TextColor color.Color // white by default
+	LineWidth int         // scaled with the image by default
+	FontScale int         // size of a font pixel, scaled with the image by default
+
+	// Text of the label of a face, Label by default
+	Label func(face alchemyapi.ImageFace) string
+
+	Format  string // "png" (default) or "jpeg", used by Render and WriteCrops
+	Quality int    // JPEG quality, jpeg.DefaultQuality by default
+
+	// Space added around the crops, as a fraction of the face size
+	CropMargin float64
+}
+
+func (opts *Options) withDefaults(bounds image.Rectangle) Options {
+	var o Options
+	if opts != nil {
+		o = *opts
+	}
+	if o.Color == nil {
+		o.Color = color.RGBA{230, 30, 30, 255}
+	}
+	if o.TextColor == nil {
+		o.TextColor = color.White
+	}
+	size := bounds.Dx()
+	if bounds.Dy() < size {
+		size = bounds.Dy()
+	}
+	if o.LineWidth <= 0 {
+		o.LineWidth = max(2, size/250)
+	}
+	if o.FontScale <= 0 {
+		o.FontScale = max(1, size/400)
+	}
+	if o.Label == nil {
+		o.Label = Label
+	}
+	if o.Format == "" {
+		o.Format = "png"
+	}
+	if o.Quality <= 0 {
+		o.Quality = jpeg.DefaultQuality
+	}
+	return o
+}
+
+// Label describes a face on up to two lines: age range and gender, then
+// the identity when the face was recognized.
+func Label(face alchemyapi.ImageFace) string {
+	var parts []string
+	if face.Age.AgeRange != "" {
+		parts = append(parts, fmt.Sprintf("%s %.2f", face.Age.AgeRange, face.Age.Score))
+	}
+	if face.Gender.Gender != "" {
+		parts = append(parts, fmt.Sprintf("%s %.2f", face.Gender.Gender, face.Gender.Score))
+	}
+	label := strings.Join(parts, " ")
+	if face.Identity.Name != "" {
+		label += fmt.Sprintf("\n%s %.2f", face.Identity.Name, face.Identity.Score)
+	}
+	return strings.TrimPrefix(label, "\n")
+}
+
+// Bounds of the face in image coordinates
+func Bounds(face alchemyapi.ImageFace) image.Rectangle {
+	return image.Rect(int(face.PositionX), int(face.PositionY),
+		int(face.PositionX+face.Width), int(face.PositionY+face.Height))
+}
+
+// Draw returns a copy of img with the faces outlined and labeled.
+func Draw(img image.Image, faces []alchemyapi.ImageFace, opts *Options) *image.RGBA {
+	bounds := img.Bounds()
+	o := opts.withDefaults(bounds)
+
+	dst := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(dst, dst.Bounds(), img, bounds.Min, draw.Src)
+
+	fill := image.NewUniform(o.Color)
+	for _, face := range faces {
+		box := Bounds(face)
+		w := o.LineWidth
+		for _, edge := range []image.Rectangle{
+			image.Rect(box.Min.X-w, box.Min.Y-w, box.Max.X+w, box.Min.Y),
+			image.Rect(box.Min.X-w, box.Max.Y, box.Max.X+w, box.Max.Y+w),
+			image.Rect(box.Min.X-w, box.Min.Y, box.Min.X, box.Max.Y),
+			image.Rect(box.Max.X, box.Min.Y, box.Max.X+w, box.Max.Y),
+		} {
+			draw.Draw(dst, edge, fill, image.Point{}, draw.Src)
+		}
+
+		label := o.Label(face)
+		if label == "" {
+			continue
+		}
+		pad := o.FontScale * 2
+		size := textSize(label, o.FontScale).Add(image.Pt(2*pad, 2*pad))
+		// above the box, below it when there is no room
+		at := image.Pt(box.Min.X-w, box.Min.Y-w-size.Y)
+		if at.Y < 0 {
+			at.Y = box.Max.Y + w
+		}
+		if at.X+size.X > dst.Bounds().Max.X {
+			at.X = dst.Bounds().Max.X - size.X
+		}
+		if at.X < 0 {
+			at.X = 0
+		}
+		draw.Draw(dst, image.Rectangle{at, at.Add(size)}, fill, image.Point{}, draw.Src)
+		drawText(dst, at.Add(image.Pt(pad, pad)), label, o.TextColor, o.FontScale)
+	}
+	return dst
+}
+
+// Render draws the faces of the response onto img and writes the result
+// as PNG or JPEG, see Options.Format.
+func Render(w io.Writer, img image.Image, response *alchemyapi.FaceResponse, opts *Options) error {
+	return Encode(w, Draw(img, response.ImageFaces, opts), opts)
+}
+
+// Encode writes img in the format of the options.
+func Encode(w io.Writer, img image.Image, opts *Options) error {
+	o := opts.withDefaults(img.Bounds())
+	switch o.Format {
+	case "png":
+		return png.Encode(w, img)
+	case "jpeg", "jpg":
+		return jpeg.Encode(w, img, &jpeg.Options{Quality: o.Quality})
+	}
+	return fmt.Errorf("annotate: unsupported format %q", o.Format)
+}
+
+// Crops cuts every face out of img, enlarged by Options.CropMargin and
+// clipped to the image. Faces outside of the image give empty crops.
+func Crops(img image.Image, faces []alchemyapi.ImageFace, opts *Options) []*image.RGBA {
+	o := opts.withDefaults(img.Bounds())
+	crops := make([]*image.RGBA, len(faces))
+	for i, face := range faces {
+		box := Bounds(face)
+		margin := image.Pt(int(float64(box.Dx())*o.CropMargin), int(float64(box.Dy())*o.CropMargin))
+		box = image.Rectangle{box.Min.Sub(margin), box.Max.Add(margin)}
+		box = box.Add(img.Bounds().Min).Intersect(img.Bounds())
+
+		crop := image.NewRGBA(image.Rect(0, 0, box.Dx(), box.Dy()))
+		draw.Draw(crop, crop.Bounds(), img, box.Min, draw.Src)
+		crops[i] = crop
+	}
+	return crops
+}
+
+// WriteCrops writes the crops of the faces to dir, named face-<index> with
+// the extension of Options.Format, and returns their paths. Faces outside
+// of the image are skipped.
+func WriteCrops(dir string, img image.Image, faces []alchemyapi.ImageFace, opts *Options) ([]string, error) {
+	o := opts.withDefaults(img.Bounds())
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	var paths []string
+	for i, crop := range Crops(img, faces, opts) {
+		if crop.Bounds().Empty() {
+			continue
+		}
+		path := filepath.Join(dir, fmt.Sprintf("face-%d.%s", i, o.Format))
+		f, err := os.Create(path)
+		if err != nil {
+			return paths, err
+		}
+		err = Encode(f, crop, &o)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return paths, err
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
@@ -0,0 +1,92 @@
+package annotate
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"os"
+	"testing"
+
+	alchemyapi "github.com/elvuel/alchemyapi_go"
+)
+
+func testFace(x, y, w, h alchemyapi.Int) alchemyapi.ImageFace {
+	var face alchemyapi.ImageFace
+	face.PositionX, face.PositionY, face.Width, face.Height = x, y, w, h
+	face.Age.AgeRange, face.Age.Score = "18-24", 0.5
+	face.Gender.Gender, face.Gender.Score = "MALE", 0.99
+	return face
+}
+
+func testImage() *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, 200, 100))
+	draw.Draw(img, img.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	return img
+}
+
+func TestLabel(t *testing.T) {
+	face := testFace(0, 0, 1, 1)
+	if got := Label(face); got != "18-24 0.50 MALE 0.99" {
+		t.Errorf("want %v, but %v", "18-24 0.50 MALE 0.99", got)
+	}
+	face.Identity.Name, face.Identity.Score = "David Beckham", 0.9
+	if got := Label(face); got != "18-24 0.50 MALE 0.99\nDavid Beckham 0.90" {
+		t.Errorf("unexpected label %q", got)
+	}
+	if got := Label(alchemyapi.ImageFace{}); got != "" {
+		t.Errorf("want empty label, but %q", got)
+	}
+}
+
+func TestDraw(t *testing.T) {
+	src := testImage()
+	red := color.RGBA{255, 0, 0, 255}
+	img := Draw(src, []alchemyapi.ImageFace{testFace(100, 50, 40, 30)}, &Options{Color: red, LineWidth: 2})
+
+	if img.RGBAAt(99, 60) != red || img.RGBAAt(120, 81) != red {
+		t.Error("the face should be outlined")
+	}
+	if img.RGBAAt(120, 65) == red {
+		t.Error("the face should be left untouched")
+	}
+	// the label sits above the box
+	if img.RGBAAt(100, 40) != red {
+		t.Error("the face should be labeled")
+	}
+	if src.RGBAAt(99, 60) == red {
+		t.Error("the source image should be untouched")
+	}
+
+	var buf bytes.Buffer
+	response := &alchemyapi.FaceResponse{ImageFaces: []alchemyapi.ImageFace{testFace(0, 0, 10, 10)}}
+	if err := Render(&buf, src, response, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := png.Decode(&buf); err != nil {
+		t.Errorf("should render a png, %v", err)
+	}
+	if err := Render(&buf, src, response, &Options{Format: "gif"}); err == nil {
+		t.Error("should raise exception for unsupported formats")
+	}
+}
+
+func TestCrops(t *testing.T) {
+	faces := []alchemyapi.ImageFace{testFace(10, 10, 20, 40), testFace(190, 90, 20, 20), testFace(300, 300, 5, 5)}
+	crops := Crops(testImage(), faces, &Options{CropMargin: 0.5})
+	if crops[0].Bounds().Dx() != 40 || crops[0].Bounds().Dy() != 70 {
+		t.Errorf("want %v, but %v", "40x70", crops[0].Bounds())
+	}
+	if crops[1].Bounds().Dx() != 20 || crops[1].Bounds().Dy() != 20 {
+		t.Errorf("the crop should be clipped, but %v", crops[1].Bounds())
+	}
+
+	paths, err := WriteCrops(t.TempDir(), testImage(), faces, &Options{Format: "jpeg"})
+	if err != nil || len(paths) != 2 {
+		t.Fatalf("unexpected crops %v, %v", paths, err)
+	}
+	if _, err := os.Stat(paths[1]); err != nil {
+		t.Errorf("the crop should be written, %v", err)
+	}
+}
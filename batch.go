@@ -0,0 +1,243 @@
+package alchemyapi
+
+/**
+  Copyright 2015 AlchemyAPI
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+      http://www.apache.org/licenses/LICENSE-2.0
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sync"
+)
+
+const DefaultBatchConcurrency = 4
+
+// BatchItem is a single input of a batch.
+type BatchItem struct {
+	Flavor   string
+	Payload  string
+	Target   string // sentiment_targeted only
+	URLParam string // feeds and microformats only, see Feeds
+	Options  url.Values
+}
+
+// BatchResult is the outcome of a single BatchItem. Response holds the
+// pointer the matching Analyzer method returns, e.g. *EntitiesResponse.
+type BatchResult struct {
+	Index    int
+	Item     BatchItem
+	Response interface{}
+	Err      error
+}
+
+// BatchOptions tunes a batch run.
+type BatchOptions struct {
+	Concurrency int                   // workers, DefaultBatchConcurrency if 0
+	OnProgress  func(done, total int) // called after every item, total is -1 for streams
+	OnResult    func(BatchResult)     // called as soon as an item is done, in completion order
+}
+
+type batchFunc func(*Analyzer, context.Context, BatchItem) (interface{}, error)
+
+// the Analyzer method behind every arrange
+var batchCalls = map[string]batchFunc{
+	"sentiment":        batchCall((*Analyzer).SentimentContext),
+	"taxonomy":         batchCall((*Analyzer).TaxonomyContext),
+	"concepts":         batchCall((*Analyzer).ConceptsContext),
+	"entities":         batchCall((*Analyzer).EntitiesContext),
+	"keywords":         batchCall((*Analyzer).KeywordsContext),
+	"relations":        batchCall((*Analyzer).RelationsContext),
+	"text":             batchCall((*Analyzer).TextContext),
+	"text_raw":         batchCall((*Analyzer).TextRawContext),
+	"title":            batchCall((*Analyzer).TitleContext),
+	"face":             batchCall((*Analyzer).FaceContext),
+	"image_extract":    batchCall((*Analyzer).ImageExtractContext),
+	"image_tag":        batchCall((*Analyzer).ImageTagContext),
+	"authors":          batchCall((*Analyzer).AuthorsContext),
+	"language":         batchCall((*Analyzer).LanguageContext),
+	"combined":         batchCall((*Analyzer).CombinedContext),
+	"publication_date": batchCall((*Analyzer).PublicationDateContext),
+	"sentiment_targeted": func(analyzer *Analyzer, ctx context.Context, item BatchItem) (interface{}, error) {
+		return nilSafe(analyzer.SentimentTargetedContext(ctx, item.Flavor, item.Payload, item.Target, item.Options))
+	},
+	"feeds": func(analyzer *Analyzer, ctx context.Context, item BatchItem) (interface{}, error) {
+		return nilSafe(analyzer.FeedsContext(ctx, item.Flavor, item.Payload, item.URLParam, item.Options))
+	},
+	"microformats": func(analyzer *Analyzer, ctx context.Context, item BatchItem) (interface{}, error) {
+		return nilSafe(analyzer.MicroformatsContext(ctx, item.Flavor, item.Payload, item.URLParam, item.Options))
+	},
+}
+
+func batchCall[T any](call func(*Analyzer, context.Context, string, string, url.Values) (*T, error)) batchFunc {
+	return func(analyzer *Analyzer, ctx context.Context, item BatchItem) (interface{}, error) {
+		return nilSafe(call(analyzer, ctx, item.Flavor, item.Payload, item.Options))
+	}
+}
+
+// Keeps a nil response a nil interface
+func nilSafe[T any](response *T, err error) (interface{}, error) {
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+/*
+   Runs the endpoint (an arrange of the entry points, e.g. "entities") for
+   every item with bounded concurrency.
+
+   The results are in input order, a failed item only sets its own Err.
+   Items not started when the context is done fail with the context error.
+   An error is only returned for an unknown endpoint.
+*/
+func (analyzer *Analyzer) Batch(ctx context.Context, arrange string, items []BatchItem, opts BatchOptions) ([]BatchResult, error) {
+	call, err := batchCallFor(arrange)
+	if err != nil {
+		return nil, err
+	}
+
+	in := make(chan BatchItem, len(items))
+	for _, item := range items {
+		in <- item
+	}
+	close(in)
+
+	results := make([]BatchResult, len(items))
+	finished := make([]bool, len(items))
+	done := 0
+	emit := func(result BatchResult) {
+		results[result.Index] = result
+		finished[result.Index] = true
+		done++
+		if opts.OnResult != nil {
+			opts.OnResult(result)
+		}
+		if opts.OnProgress != nil {
+			opts.OnProgress(done, len(items))
+		}
+	}
+	analyzer.runBatch(ctx, call, in, opts.Concurrency, emit)
+
+	// the items never handed to a worker once the context is done
+	for i, item := range items {
+		if !finished[i] {
+			emit(BatchResult{Index: i, Item: item, Err: ctx.Err()})
+		}
+	}
+	return results, nil
+}
+
+/*
+   Like Batch for inputs arriving on a channel. The results are sent in
+   completion order, Index is the position of the item on the input
+   channel. The returned channel is closed once the input channel is
+   closed and every item is done, or once the context is done; results
+   not received by then are dropped.
+*/
+func (analyzer *Analyzer) BatchStream(ctx context.Context, arrange string, items <-chan BatchItem, opts BatchOptions) (<-chan BatchResult, error) {
+	call, err := batchCallFor(arrange)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(chan BatchResult)
+	go func() {
+		defer close(out)
+		done := 0
+		analyzer.runBatch(ctx, call, items, opts.Concurrency, func(result BatchResult) {
+			done++
+			if opts.OnResult != nil {
+				opts.OnResult(result)
+			}
+			if opts.OnProgress != nil {
+				opts.OnProgress(done, -1)
+			}
+			select {
+			case out <- result:
+			case <-ctx.Done():
+			}
+		})
+	}()
+	return out, nil
+}
+
+func batchCallFor(arrange string) (batchFunc, error) {
+	call, ok := batchCalls[arrange]
+	if !ok || !entryPoints.hasArrange(arrange) {
+		return nil, fmt.Errorf("batch for %s not available", arrange)
+	}
+	return call, nil
+}
+
+// Feeds the items to the workers, emit is called from a single goroutine.
+// Once the context is done no more items are read, it returns when the
+// items already handed to a worker are done.
+func (analyzer *Analyzer) runBatch(ctx context.Context, call batchFunc, items <-chan BatchItem, concurrency int, emit func(BatchResult)) {
+	if concurrency <= 0 {
+		concurrency = DefaultBatchConcurrency
+	}
+
+	type job struct {
+		index int
+		item  BatchItem
+	}
+
+	jobs := make(chan job)
+	results := make(chan BatchResult)
+
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				result := BatchResult{Index: j.index, Item: j.item}
+				if err := ctx.Err(); err != nil {
+					result.Err = err
+				} else {
+					result.Response, result.Err = call(analyzer, ctx, j.item)
+				}
+				results <- result
+			}
+		}()
+	}
+
+	go func() {
+		defer func() {
+			close(jobs)
+			wg.Wait()
+			close(results)
+		}()
+		for index := 0; ; index++ {
+			var item BatchItem
+			var ok bool
+			select {
+			case item, ok = <-items:
+				if !ok {
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+			select {
+			case jobs <- job{index: index, item: item}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	for result := range results {
+		emit(result)
+	}
+}
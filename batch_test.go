@@ -0,0 +1,165 @@
+package alchemyapi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newBatchServer(peak *int32) *httptest.Server {
+	var current int32
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		n := atomic.AddInt32(&current, 1)
+		defer atomic.AddInt32(&current, -1)
+		for {
+			p := atomic.LoadInt32(peak)
+			if n <= p || atomic.CompareAndSwapInt32(peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+
+		switch r.FormValue("text") {
+		case "bad":
+			w.Write([]byte("{\"status\":\"ERROR\",\"statusInfo\":\"unsupported-text-language\"}"))
+		default:
+			w.Write([]byte("{\"status\":\"OK\",\"text\":\"" + r.FormValue("text") + "\"}"))
+		}
+	}
+	return httptest.NewServer(http.HandlerFunc(handler))
+}
+
+func TestAnalyzerBatch(t *testing.T) {
+	var peak int32
+	server := newBatchServer(&peak)
+	defer server.Close()
+
+	analyzer, _ := NewAnalyzer("foooooooooooooooooooooooooooooooooooobar", WithBaseURL(server.URL))
+
+	payloads := []string{"a", "b", "bad", "d", "e", "f", "g", "h"}
+	items := make([]BatchItem, len(payloads))
+	for i, v := range payloads {
+		items[i] = BatchItem{Flavor: "text", Payload: v}
+	}
+
+	var progress, streamed int
+	results, err := analyzer.Batch(context.Background(), "entities", items, BatchOptions{
+		Concurrency: 3,
+		OnProgress: func(done, total int) {
+			progress = done
+			if total != len(items) {
+				t.Errorf("want %d, but %d", len(items), total)
+			}
+		},
+		OnResult: func(BatchResult) { streamed++ },
+	})
+	if err != nil {
+		t.Fatalf("should not raise exception, %v", err)
+	}
+
+	if peak > 3 {
+		t.Errorf("want at most %d in flight, but %d", 3, peak)
+	}
+	if progress != len(items) || streamed != len(items) {
+		t.Errorf("want %d, but progress %d, streamed %d", len(items), progress, streamed)
+	}
+
+	for i, result := range results {
+		if result.Index != i || result.Item.Payload != payloads[i] {
+			t.Errorf("result %d out of order: %+v", i, result)
+		}
+		if payloads[i] == "bad" {
+			if !errors.Is(result.Err, ErrUnsupportedTextLanguage) {
+				t.Errorf("want %v, but %v", ErrUnsupportedTextLanguage, result.Err)
+			}
+			continue
+		}
+		resp, ok := result.Response.(*EntitiesResponse)
+		if result.Err != nil || !ok || resp.Text != payloads[i] {
+			t.Errorf("result %d want %s, but %+v", i, payloads[i], result)
+		}
+	}
+}
+
+func TestAnalyzerBatchStream(t *testing.T) {
+	var peak int32
+	server := newBatchServer(&peak)
+	defer server.Close()
+
+	analyzer, _ := NewAnalyzer("foooooooooooooooooooooooooooooooooooobar", WithBaseURL(server.URL))
+
+	in := make(chan BatchItem)
+	go func() {
+		for _, v := range []string{"a", "b", "c"} {
+			in <- BatchItem{Flavor: "text", Payload: v, Options: url.Values{"sentiment": {"1"}}}
+		}
+		close(in)
+	}()
+
+	out, err := analyzer.BatchStream(context.Background(), "keywords", in, BatchOptions{})
+	if err != nil {
+		t.Fatalf("should not raise exception, %v", err)
+	}
+
+	seen := map[int]bool{}
+	for result := range out {
+		if result.Err != nil {
+			t.Errorf("should not raise exception, %v", result.Err)
+		}
+		seen[result.Index] = true
+	}
+	if len(seen) != 3 {
+		t.Errorf("want %d results, but %d", 3, len(seen))
+	}
+}
+
+func TestAnalyzerBatchUnknownEndpoint(t *testing.T) {
+	analyzer, _ := NewAnalyzer("foooooooooooooooooooooooooooooooooooobar")
+	if _, err := analyzer.Batch(context.Background(), "foo", nil, BatchOptions{}); err == nil {
+		t.Error("should be error")
+	}
+}
+
+func TestAnalyzerBatchCancel(t *testing.T) {
+	analyzer, _ := NewAnalyzer("foooooooooooooooooooooooooooooooooooobar")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	results, _ := analyzer.Batch(ctx, "entities", []BatchItem{{Flavor: "text", Payload: "a"}}, BatchOptions{})
+	if !errors.Is(results[0].Err, context.Canceled) {
+		t.Errorf("want %v, but %v", context.Canceled, results[0].Err)
+	}
+}
+
+func TestAnalyzerBatchStreamCancel(t *testing.T) {
+	var peak int32
+	server := newBatchServer(&peak)
+	defer server.Close()
+
+	analyzer, _ := NewAnalyzer("foooooooooooooooooooooooooooooooooooobar", WithBaseURL(server.URL))
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// never closed
+	in := make(chan BatchItem)
+	out, _ := analyzer.BatchStream(ctx, "keywords", in, BatchOptions{})
+	in <- BatchItem{Flavor: "text", Payload: "a"}
+	cancel()
+
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case _, ok := <-out:
+			if !ok {
+				return
+			}
+		case <-timeout:
+			t.Fatal("the results should be closed once the context is done")
+		}
+	}
+}
@@ -0,0 +1,227 @@
+package alchemyapi
+
+/**
+  Copyright 2015 AlchemyAPI
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+      http://www.apache.org/licenses/LICENSE-2.0
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Cache stores successful responses so identical calls do not spend
+// transactions again, see WithCache. Implementations must be safe for
+// concurrent use.
+type Cache interface {
+	Get(key string) ([]byte, bool)
+	Set(key string, data []byte)
+}
+
+// CacheStats counts the cache lookups of an Analyzer.
+type CacheStats struct {
+	Hits   int64
+	Misses int64
+}
+
+type cacheCounters struct {
+	hits   int64
+	misses int64
+}
+
+// Serves identical calls from the cache. The key is built from the
+// endpoint, the flavor, the payload and the options, the api key left out.
+func WithCache(cache Cache) Option {
+	return func(analyzer *Analyzer) {
+		analyzer.cache = cache
+	}
+}
+
+// Returns the cache hits and misses so far.
+func (analyzer *Analyzer) CacheStats() CacheStats {
+	return CacheStats{
+		Hits:   atomic.LoadInt64(&analyzer.cacheCounters.hits),
+		Misses: atomic.LoadInt64(&analyzer.cacheCounters.misses),
+	}
+}
+
+// Builds the cache key of a call, options must not hold the api key yet
+func cacheKey(arrange, flavor string, options url.Values, binData []byte) string {
+	keys := make([]string, 0, len(options))
+	for k := range options {
+		if k == "apikey" || k == "outputMode" {
+			continue
+		}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	hash := sha256.New()
+	hash.Write([]byte(arrange + "\n" + flavor + "\n"))
+	for _, k := range keys {
+		for _, v := range options[k] {
+			if k == flavor {
+				v = strings.TrimSpace(v)
+			}
+			hash.Write([]byte(url.QueryEscape(k) + "=" + url.QueryEscape(v) + "\n"))
+		}
+	}
+	if binData != nil {
+		sum := sha256.Sum256(binData)
+		hash.Write(sum[:])
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// MemoryCache is an in-memory LRU Cache with an optional TTL.
+type MemoryCache struct {
+	mu       sync.Mutex
+	capacity int
+	ttl      time.Duration
+	entries  map[string]*list.Element
+	lru      *list.List
+}
+
+type memoryEntry struct {
+	key     string
+	data    []byte
+	expires time.Time
+}
+
+// Creates new MemoryCache holding at most capacity responses, each for at
+// most ttl (0 means no expiry).
+func NewMemoryCache(capacity int, ttl time.Duration) *MemoryCache {
+	if capacity < 1 {
+		capacity = 1
+	}
+	return &MemoryCache{
+		capacity: capacity,
+		ttl:      ttl,
+		entries:  make(map[string]*list.Element),
+		lru:      list.New(),
+	}
+}
+
+func (cache *MemoryCache) Get(key string) ([]byte, bool) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	element, ok := cache.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	entry := element.Value.(*memoryEntry)
+	if !entry.expires.IsZero() && time.Now().After(entry.expires) {
+		cache.lru.Remove(element)
+		delete(cache.entries, key)
+		return nil, false
+	}
+	cache.lru.MoveToFront(element)
+	return entry.data, true
+}
+
+func (cache *MemoryCache) Set(key string, data []byte) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	var expires time.Time
+	if cache.ttl > 0 {
+		expires = time.Now().Add(cache.ttl)
+	}
+
+	if element, ok := cache.entries[key]; ok {
+		element.Value = &memoryEntry{key: key, data: data, expires: expires}
+		cache.lru.MoveToFront(element)
+		return
+	}
+
+	cache.entries[key] = cache.lru.PushFront(&memoryEntry{key: key, data: data, expires: expires})
+	for cache.lru.Len() > cache.capacity {
+		oldest := cache.lru.Back()
+		cache.lru.Remove(oldest)
+		delete(cache.entries, oldest.Value.(*memoryEntry).key)
+	}
+}
+
+// Number of cached responses, expired ones included.
+func (cache *MemoryCache) Len() int {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	return cache.lru.Len()
+}
+
+// FileCache is an on-disk Cache storing a file per key in a directory,
+// expired by modification time.
+type FileCache struct {
+	dir string
+	ttl time.Duration
+}
+
+// Creates new FileCache in dir (created if missing), each response is kept
+// for at most ttl (0 means no expiry).
+func NewFileCache(dir string, ttl time.Duration) (*FileCache, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &FileCache{dir: dir, ttl: ttl}, nil
+}
+
+func (cache *FileCache) Get(key string) ([]byte, bool) {
+	path := cache.path(key)
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, false
+	}
+	if cache.ttl > 0 && time.Since(info.ModTime()) > cache.ttl {
+		os.Remove(path)
+		return nil, false
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	return data, true
+}
+
+// Writes to a temp file renamed into place, so readers never see a partial file
+func (cache *FileCache) Set(key string, data []byte) {
+	tmp, err := ioutil.TempFile(cache.dir, ".tmp-")
+	if err != nil {
+		return
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return
+	}
+	if err := os.Rename(tmp.Name(), cache.path(key)); err != nil {
+		os.Remove(tmp.Name())
+	}
+}
+
+func (cache *FileCache) path(key string) string {
+	return filepath.Join(cache.dir, key+".json")
+}
@@ -0,0 +1,102 @@
+package alchemyapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCacheKey(t *testing.T) {
+	a := url.Values{"text": {" foobar "}, "sentiment": {"1"}, "maxRetrieve": {"5"}}
+	b := url.Values{"maxRetrieve": {"5"}, "sentiment": {"1"}, "text": {"foobar"}, "apikey": {"secret"}}
+	if cacheKey("entities", "text", a, nil) != cacheKey("entities", "text", b, nil) {
+		t.Error("equivalent calls should share the key")
+	}
+
+	if cacheKey("entities", "text", a, nil) == cacheKey("keywords", "text", a, nil) {
+		t.Error("endpoints should not share the key")
+	}
+
+	if cacheKey("face", "image", url.Values{}, []byte("a")) == cacheKey("face", "image", url.Values{}, []byte("b")) {
+		t.Error("images should not share the key")
+	}
+}
+
+func TestMemoryCache(t *testing.T) {
+	cache := NewMemoryCache(2, 0)
+	cache.Set("a", []byte("1"))
+	cache.Set("b", []byte("2"))
+	cache.Get("a")
+	cache.Set("c", []byte("3"))
+
+	if _, ok := cache.Get("b"); ok {
+		t.Error("the least recently used entry should be evicted")
+	}
+	if data, ok := cache.Get("a"); !ok || string(data) != "1" {
+		t.Errorf("want %s, but %s", "1", data)
+	}
+	if cache.Len() != 2 {
+		t.Errorf("want %d, but %d", 2, cache.Len())
+	}
+
+	cache = NewMemoryCache(2, time.Millisecond)
+	cache.Set("a", []byte("1"))
+	time.Sleep(5 * time.Millisecond)
+	if _, ok := cache.Get("a"); ok {
+		t.Error("the entry should be expired")
+	}
+}
+
+func TestFileCache(t *testing.T) {
+	cache, err := NewFileCache(t.TempDir(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := cache.Get("a"); ok {
+		t.Error("should be missing")
+	}
+	cache.Set("a", []byte("1"))
+	if data, ok := cache.Get("a"); !ok || string(data) != "1" {
+		t.Errorf("want %s, but %s", "1", data)
+	}
+
+	cache.ttl = time.Nanosecond
+	time.Sleep(time.Millisecond)
+	if _, ok := cache.Get("a"); ok {
+		t.Error("the entry should be expired")
+	}
+}
+
+func TestAnalyzerCache(t *testing.T) {
+	apiKey := "foooooooooooooooooooooooooooooooooooobar"
+	var calls int32
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.Write([]byte("{\"status\":\"OK\",\"language\":\"english\"}"))
+	}
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	analyzer, _ := NewAnalyzer(apiKey, WithBaseURL(server.URL), WithCache(NewMemoryCache(10, time.Minute)))
+	for i := 0; i < 3; i++ {
+		resp, err := analyzer.Language("text", "foobar", url.Values{})
+		if err != nil || resp.Language != "english" {
+			t.Fatalf("should not raise exception, %v", err)
+		}
+	}
+	analyzer.Language("text", "other", url.Values{})
+
+	if calls != 2 {
+		t.Errorf("want %d, but %d", 2, calls)
+	}
+	if stats := analyzer.CacheStats(); stats.Hits != 2 || stats.Misses != 2 {
+		t.Errorf("want 2 hits and 2 misses, but %+v", stats)
+	}
+	if got := analyzer.Ledger().Total(); got != 2 {
+		t.Errorf("cache hits should not be accounted, want %d, but %d", 2, got)
+	}
+}
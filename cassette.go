@@ -0,0 +1,240 @@
+package alchemyapi
+
+/**
+  Copyright 2015 AlchemyAPI
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+      http://www.apache.org/licenses/LICENSE-2.0
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sync"
+)
+
+var ErrCassetteMiss = errors.New("request not recorded in cassette")
+
+type CassetteMode int
+
+const (
+	// Serves the recorded responses, never touches the network.
+	CassetteReplay CassetteMode = iota
+	// Sends the requests and records them, overwriting the file.
+	CassetteRecord
+)
+
+// Interaction is a recorded request/response pair, the apikey scrubbed.
+type Interaction struct {
+	Method     string      `json:"method"`
+	Path       string      `json:"path"`
+	Query      string      `json:"query,omitempty"`
+	Body       string      `json:"body"` // the form, or sha256:... of an image
+	StatusCode int         `json:"statusCode"`
+	Header     http.Header `json:"header,omitempty"`
+	Response   string      `json:"response"`
+}
+
+// Cassette records the calls of an Analyzer to a file and replays them
+// later without network access, see WithCassette. A cassette belongs to a
+// single Analyzer.
+type Cassette struct {
+	mu           sync.Mutex
+	path         string
+	mode         CassetteMode
+	interactions []Interaction
+	replayed     []bool
+	next         http.RoundTripper
+}
+
+// Creates new Cassette backed by the file at path, which must exist in
+// replay mode.
+func NewCassette(path string, mode CassetteMode) (*Cassette, error) {
+	cassette := &Cassette{path: path, mode: mode}
+	if mode == CassetteReplay {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(data, &cassette.interactions); err != nil {
+			return nil, fmt.Errorf("cassette %s: %s", path, err)
+		}
+		cassette.replayed = make([]bool, len(cassette.interactions))
+	}
+	return cassette, nil
+}
+
+// Records to or replays from the cassette. Recording goes through the
+// transport of the analyzer's client, whichever option set it.
+func WithCassette(cassette *Cassette) Option {
+	return func(analyzer *Analyzer) {
+		analyzer.cassette = cassette
+	}
+}
+
+// The recorded interactions.
+func (cassette *Cassette) Interactions() []Interaction {
+	cassette.mu.Lock()
+	defer cassette.mu.Unlock()
+	return append([]Interaction(nil), cassette.interactions...)
+}
+
+// Wraps the client so its requests go through the cassette
+func (cassette *Cassette) wrap(client *http.Client) *http.Client {
+	wrapped := *client
+	cassette.next = client.Transport
+	if cassette.next == nil {
+		cassette.next = http.DefaultTransport
+	}
+	wrapped.Transport = cassette
+	return &wrapped
+}
+
+func (cassette *Cassette) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		if body, err = ioutil.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+	}
+	key := interactionFor(req, body)
+
+	if cassette.mode == CassetteReplay {
+		return cassette.replay(req, key)
+	}
+
+	req = req.Clone(req.Context())
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	resp, err := cassette.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := readAllDecoded(resp)
+	if err != nil {
+		return nil, err
+	}
+
+	key.StatusCode = resp.StatusCode
+	key.Header = resp.Header.Clone()
+	key.Header.Del("Content-Encoding")
+	key.Header.Del("Content-Length")
+	key.Response = string(data)
+	if err := cassette.record(key); err != nil {
+		return nil, err
+	}
+	return key.response(req), nil
+}
+
+func (cassette *Cassette) replay(req *http.Request, key Interaction) (*http.Response, error) {
+	cassette.mu.Lock()
+	defer cassette.mu.Unlock()
+
+	// the first interaction not replayed yet wins, then any match
+	match := -1
+	for i, v := range cassette.interactions {
+		if v.Method == key.Method && v.Path == key.Path && v.Query == key.Query && v.Body == key.Body {
+			if !cassette.replayed[i] {
+				match = i
+				break
+			}
+			if match < 0 {
+				match = i
+			}
+		}
+	}
+	if match < 0 {
+		return nil, fmt.Errorf("%w: %s %s", ErrCassetteMiss, key.Method, key.Path)
+	}
+
+	cassette.replayed[match] = true
+	return cassette.interactions[match].response(req), nil
+}
+
+func (cassette *Cassette) record(interaction Interaction) error {
+	cassette.mu.Lock()
+	defer cassette.mu.Unlock()
+
+	cassette.interactions = append(cassette.interactions, interaction)
+	data, err := json.MarshalIndent(cassette.interactions, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(cassette.path, data, 0644)
+}
+
+// The request side of an interaction, apikey scrubbed
+func interactionFor(req *http.Request, body []byte) Interaction {
+	interaction := Interaction{
+		Method: req.Method,
+		Path:   req.URL.Path,
+		Query:  scrubAPIKey(req.URL.RawQuery),
+	}
+	if req.URL.RawQuery != "" {
+		// image flavor, the options live in the query
+		sum := sha256.Sum256(body)
+		interaction.Body = "sha256:" + hex.EncodeToString(sum[:])
+	} else {
+		interaction.Body = scrubAPIKey(string(body))
+	}
+	return interaction
+}
+
+// Drops the apikey of the form; a form which does not parse is replaced
+// as a whole, it may hold the key
+func scrubAPIKey(form string) string {
+	values, err := url.ParseQuery(form)
+	if err != nil {
+		return "(unparsable form)"
+	}
+	values.Del("apikey")
+	return values.Encode()
+}
+
+func (interaction Interaction) response(req *http.Request) *http.Response {
+	header := interaction.Header.Clone()
+	if header == nil {
+		header = make(http.Header)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", interaction.StatusCode, http.StatusText(interaction.StatusCode)),
+		StatusCode:    interaction.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(bytes.NewReader([]byte(interaction.Response))),
+		ContentLength: int64(len(interaction.Response)),
+		Request:       req,
+	}
+}
+
+func readAllDecoded(resp *http.Response) ([]byte, error) {
+	defer resp.Body.Close()
+	if resp.Header.Get("Content-Encoding") != "gzip" {
+		return ioutil.ReadAll(resp.Body)
+	}
+
+	reader, err := gzip.NewReader(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return ioutil.ReadAll(reader)
+}
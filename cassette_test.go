@@ -0,0 +1,87 @@
+package alchemyapi
+
+import (
+	"compress/gzip"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCassetteRecordAndReplay(t *testing.T) {
+	apiKey := "foooooooooooooooooooooooooooooooooooobar"
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		w.Header().Set("Content-Encoding", "gzip")
+		writer := gzip.NewWriter(w)
+		writer.Write([]byte("{\"status\":\"OK\",\"language\":\"english\",\"text\":\"" + r.FormValue("text") + "\"}"))
+		writer.Close()
+	}
+	server := httptest.NewServer(http.HandlerFunc(handler))
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "cassette.json")
+	image := filepath.Join(dir, "face.jpg")
+	ioutil.WriteFile(image, []byte("jpeg"), 0644)
+
+	recorder, _ := NewCassette(path, CassetteRecord)
+	analyzer, _ := NewAnalyzer(apiKey, WithBaseURL(server.URL), WithCassette(recorder))
+	if resp, err := analyzer.Sentiment("text", "foobar", url.Values{}); err != nil || resp.Text != "foobar" {
+		t.Fatalf("should not raise exception, %v", err)
+	}
+	if _, err := analyzer.Face("image", image, url.Values{}); err != nil {
+		t.Fatalf("should not raise exception, %v", err)
+	}
+	server.Close()
+
+	data, _ := os.ReadFile(path)
+	if strings.Contains(string(data), apiKey) {
+		t.Error("the apikey should be scrubbed")
+	}
+	if len(recorder.Interactions()) != 2 {
+		t.Errorf("want %d, but %d", 2, len(recorder.Interactions()))
+	}
+
+	// the server is gone, everything comes from the cassette
+	player, err := NewCassette(path, CassetteReplay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey := strings.Repeat("x", 40)
+	analyzer, _ = NewAnalyzer(otherKey, WithBaseURL(server.URL), WithCassette(player))
+	resp, err := analyzer.Sentiment("text", "foobar", url.Values{})
+	if err != nil {
+		t.Fatalf("should not raise exception, %v", err)
+	}
+	if resp.Text != "foobar" || resp.Language != "english" {
+		t.Errorf("unexpected response %+v", resp)
+	}
+	if _, err := analyzer.Face("image", image, url.Values{}); err != nil {
+		t.Errorf("should not raise exception, %v", err)
+	}
+
+	_, err = analyzer.Sentiment("text", "never recorded", url.Values{})
+	if !errors.Is(err, ErrCassetteMiss) {
+		t.Errorf("want %v, but %v", ErrCassetteMiss, err)
+	}
+}
+
+func TestNewCassetteReplayMissing(t *testing.T) {
+	if _, err := NewCassette(filepath.Join(t.TempDir(), "missing.json"), CassetteReplay); err == nil {
+		t.Error("should be error")
+	}
+}
+
+func TestScrubAPIKey(t *testing.T) {
+	if got := scrubAPIKey("apikey=secret&text=foo"); got != "text=foo" {
+		t.Errorf("want %v, but %v", "text=foo", got)
+	}
+	if got := scrubAPIKey("apikey=secret&text=%zz"); strings.Contains(got, "secret") {
+		t.Errorf("an unparsable form should not keep the key, but %v", got)
+	}
+}
@@ -0,0 +1,400 @@
+package alchemyapi
+
+/**
+  Copyright 2015 AlchemyAPI
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+      http://www.apache.org/licenses/LICENSE-2.0
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// The default chunk size, a margin below the size limit of the text calls
+const DefaultChunkSize = 45000
+
+// ChunkOptions tunes the chunking enabled with WithChunking.
+type ChunkOptions struct {
+	MaxBytes    int // largest chunk, DefaultChunkSize if 0
+	Concurrency int // chunks analyzed at once, DefaultBatchConcurrency if 0
+}
+
+/*
+Splits text payloads larger than MaxBytes for Sentiment, Entities,
+Keywords and Concepts. The text is cut at paragraph, line, sentence or
+word boundaries (whichever keeps the chunks below the limit), the chunks
+are analyzed concurrently and the results merged into a single response:
+
+  - the document sentiment is the average weighted by chunk length,
+    mixed when the chunks disagree
+  - entities, keywords and concepts found in several chunks are merged,
+    entity counts summed, and ranked by their relevance weighted by chunk
+    length, cut to maxRetrieve when given
+
+Html payloads larger than MaxBytes are reduced to their text first:
+scripts, styles, comments and tags are dropped, block elements end
+paragraphs. The chunks of that text are sent with the text flavor.
+
+Each chunk is a call of its own for the ledger, the cache and the
+limits. Url payloads are never chunked.
+*/
+func WithChunking(opts ChunkOptions) Option {
+	return func(analyzer *Analyzer) {
+		if opts.MaxBytes <= 0 {
+			opts.MaxBytes = DefaultChunkSize
+		}
+		analyzer.chunking = &opts
+	}
+}
+
+// The text chunks of the payload, nil when it is to be sent as is
+func (analyzer *Analyzer) chunks(flavor, payload string) []string {
+	if analyzer.chunking == nil || len(payload) <= analyzer.chunking.MaxBytes {
+		return nil
+	}
+	switch flavor {
+	case "text":
+		return splitText(payload, analyzer.chunking.MaxBytes)
+	case "html":
+		return splitText(htmlText(payload), analyzer.chunking.MaxBytes)
+	}
+	return nil
+}
+
+var (
+	htmlDropped    = regexp.MustCompile(`(?is)<!--.*?-->|<(script|style|noscript|template)\b[^>]*>.*?</(script|style|noscript|template)\s*>`)
+	htmlWhitespace = regexp.MustCompile(`\s+`) // the line breaks of the source are no text
+	htmlBlocks     = regexp.MustCompile(`(?i)</?(p|div|h[1-6]|li|ul|ol|dl|dt|dd|tr|table|section|article|aside|header|footer|blockquote|pre|figure|figcaption)\b[^>]*>`)
+	htmlBreaks     = regexp.MustCompile(`(?i)<br\b[^>]*>`)
+	htmlTags       = regexp.MustCompile(`<[^>]*>`)
+	htmlSpaces     = regexp.MustCompile(`[ \t\r\f\v]+`)
+	htmlBlank      = regexp.MustCompile(`\s*\n\s*\n\s*`)
+)
+
+// The text of an html document, block elements separated by blank lines
+func htmlText(document string) string {
+	text := htmlDropped.ReplaceAllString(document, " ")
+	text = htmlWhitespace.ReplaceAllString(text, " ")
+	text = htmlBlocks.ReplaceAllString(text, "\n\n")
+	text = htmlBreaks.ReplaceAllString(text, "\n")
+	text = htmlTags.ReplaceAllString(text, " ")
+	text = htmlSpaces.ReplaceAllString(html.UnescapeString(text), " ")
+	text = htmlBlank.ReplaceAllString(text, "\n\n")
+	return strings.TrimSpace(text)
+}
+
+var textBoundaries = []*regexp.Regexp{
+	regexp.MustCompile(`\n[ \t]*\n\s*`),    // paragraphs
+	regexp.MustCompile(`\n\s*`),            // lines, e.g. markdown headings and lists
+	regexp.MustCompile(`[.!?]["')\]]*\s+`), // sentences
+	regexp.MustCompile(`\s+`),              // words
+}
+
+// Splits text into chunks of at most limit bytes, at the coarsest boundary
+// possible. Blank chunks are dropped, otherwise the chunks add up to text.
+func splitText(text string, limit int) []string {
+	var chunks []string
+	for _, chunk := range splitAt(text, limit, textBoundaries) {
+		if strings.TrimSpace(chunk) != "" {
+			chunks = append(chunks, chunk)
+		}
+	}
+	return chunks
+}
+
+func splitAt(text string, limit int, boundaries []*regexp.Regexp) []string {
+	if len(text) <= limit {
+		return []string{text}
+	}
+	if len(boundaries) == 0 {
+		return splitRunes(text, limit)
+	}
+
+	// the pieces keep the boundary they end with
+	var pieces []string
+	start := 0
+	for _, loc := range boundaries[0].FindAllStringIndex(text, -1) {
+		if loc[1] < len(text) {
+			pieces = append(pieces, text[start:loc[1]])
+			start = loc[1]
+		}
+	}
+	pieces = append(pieces, text[start:])
+
+	// packs as many pieces as fit into a chunk
+	var chunks []string
+	current := ""
+	for _, piece := range pieces {
+		if len(current)+len(piece) <= limit {
+			current += piece
+			continue
+		}
+		if current != "" {
+			chunks = append(chunks, current)
+			current = ""
+		}
+		if len(piece) <= limit {
+			current = piece
+		} else {
+			chunks = append(chunks, splitAt(piece, limit, boundaries[1:])...)
+		}
+	}
+	if current != "" {
+		chunks = append(chunks, current)
+	}
+	return chunks
+}
+
+// Last resort for text without any boundary, never cutting a rune
+func splitRunes(text string, limit int) []string {
+	var chunks []string
+	for len(text) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(text[cut]) {
+			cut--
+		}
+		if cut == 0 {
+			cut = limit
+		}
+		chunks = append(chunks, text[:cut])
+		text = text[cut:]
+	}
+	return append(chunks, text)
+}
+
+// Analyzes the chunks concurrently, failing with the first error. The
+// calls of the chunks make up one method of the arrange.
+func (analyzer *Analyzer) analyzeChunks(ctx context.Context, arrange string, call batchFunc, chunks []string, options url.Values) (results []BatchResult, err error) {
+	var payloadSize int
+	for _, chunk := range chunks {
+		payloadSize += len(chunk)
+	}
+	ctx, end := analyzer.startMethod(ctx, arrange, "text", payloadSize)
+	defer func() { end(err) }()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	items := make(chan BatchItem, len(chunks))
+	for _, chunk := range chunks {
+		items <- BatchItem{Flavor: "text", Payload: chunk, Options: options}
+	}
+	close(items)
+
+	results = make([]BatchResult, len(chunks))
+	analyzer.runBatch(ctx, call, items, analyzer.chunking.Concurrency, func(result BatchResult) {
+		results[result.Index] = result
+		if result.Err != nil && err == nil {
+			err = fmt.Errorf("chunk %d of %d: %w", result.Index+1, len(chunks), result.Err)
+			cancel()
+		}
+	})
+	if err == nil {
+		// chunks dropped once the caller gave up
+		err = ctx.Err()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+func (analyzer *Analyzer) sentimentChunks(ctx context.Context, chunks []string, options url.Values) (*SentimentResponse, error) {
+	results, err := analyzer.analyzeChunks(ctx, "sentiment", batchCall((*Analyzer).SentimentContext), chunks, options)
+	if err != nil {
+		return nil, err
+	}
+
+	merged := new(SentimentResponse)
+	var weight float64
+	for i, result := range results {
+		response := result.Response.(*SentimentResponse)
+		if i == 0 {
+			merged.Language, merged.Status, merged.Usage = response.Language, response.Status, response.Usage
+		}
+		merged.TotalTransactions += response.TotalTransactions
+		merged.DocSentiment = mergeSentiment(merged.DocSentiment, weight, response.DocSentiment, float64(len(chunks[i])))
+		weight += float64(len(chunks[i]))
+	}
+	return merged, nil
+}
+
+func (analyzer *Analyzer) entitiesChunks(ctx context.Context, chunks []string, options url.Values) (*EntitiesResponse, error) {
+	results, err := analyzer.analyzeChunks(ctx, "entities", batchCall((*Analyzer).EntitiesContext), chunks, options)
+	if err != nil {
+		return nil, err
+	}
+
+	merged := new(EntitiesResponse)
+	ranking := newRanking[Entity](chunks)
+	for i, result := range results {
+		response := result.Response.(*EntitiesResponse)
+		if i == 0 {
+			merged.Language, merged.Status, merged.Usage = response.Language, response.Status, response.Usage
+		}
+		merged.TotalTransactions += response.TotalTransactions
+		for _, entity := range response.Entities {
+			into, found := ranking.add(i, entity.Type+"\x00"+strings.ToLower(entity.Text), entity, entity.Relevance)
+			if found {
+				into.Sentiment = mergeSentiment(into.Sentiment, float64(into.Count), entity.Sentiment, float64(entity.Count))
+				into.Count += entity.Count
+				into.Quotations = append(into.Quotations, entity.Quotations...)
+			}
+		}
+	}
+
+	for _, ranked := range ranking.ranked(options) {
+		ranked.item.Relevance = ranked.relevance
+		merged.Entities = append(merged.Entities, ranked.item)
+	}
+	return merged, nil
+}
+
+func (analyzer *Analyzer) keywordsChunks(ctx context.Context, chunks []string, options url.Values) (*KeywordsResponse, error) {
+	results, err := analyzer.analyzeChunks(ctx, "keywords", batchCall((*Analyzer).KeywordsContext), chunks, options)
+	if err != nil {
+		return nil, err
+	}
+
+	merged := new(KeywordsResponse)
+	ranking := newRanking[Keyword](chunks)
+	for i, result := range results {
+		response := result.Response.(*KeywordsResponse)
+		if i == 0 {
+			merged.Language, merged.Status, merged.Usage = response.Language, response.Status, response.Usage
+		}
+		for _, keyword := range response.Keywords {
+			key := strings.ToLower(keyword.Text)
+			weight := ranking.weights[key]
+			into, found := ranking.add(i, key, keyword, keyword.Relevance)
+			if found {
+				into.Sentiment = mergeSentiment(into.Sentiment, weight, keyword.Sentiment, float64(len(chunks[i])))
+			}
+		}
+	}
+
+	for _, ranked := range ranking.ranked(options) {
+		ranked.item.Relevance = ranked.relevance
+		merged.Keywords = append(merged.Keywords, ranked.item)
+	}
+	return merged, nil
+}
+
+func (analyzer *Analyzer) conceptsChunks(ctx context.Context, chunks []string, options url.Values) (*ConceptsResponse, error) {
+	results, err := analyzer.analyzeChunks(ctx, "concepts", batchCall((*Analyzer).ConceptsContext), chunks, options)
+	if err != nil {
+		return nil, err
+	}
+
+	merged := new(ConceptsResponse)
+	ranking := newRanking[Concept](chunks)
+	for i, result := range results {
+		response := result.Response.(*ConceptsResponse)
+		if i == 0 {
+			merged.Language, merged.Status, merged.Usage = response.Language, response.Status, response.Usage
+		}
+		for _, concept := range response.Concepts {
+			ranking.add(i, strings.ToLower(concept.Text), concept, concept.Relevance)
+		}
+	}
+
+	for _, ranked := range ranking.ranked(options) {
+		ranked.item.Relevance = ranked.relevance
+		merged.Concepts = append(merged.Concepts, ranked.item)
+	}
+	return merged, nil
+}
+
+// Merges the items found in the chunks, the relevance of an item is its
+// relevance in every chunk weighted by the chunk length, so items found
+// throughout the text outrank items relevant to a single chunk.
+type ranking[T any] struct {
+	lengths []float64
+	total   float64
+	items   []*rankedItem[T]
+	byKey   map[string]*rankedItem[T]
+	weights map[string]float64 // summed length of the chunks an item was found in
+}
+
+type rankedItem[T any] struct {
+	item      T
+	relevance Float
+}
+
+func newRanking[T any](chunks []string) *ranking[T] {
+	r := &ranking[T]{
+		byKey:   make(map[string]*rankedItem[T]),
+		weights: make(map[string]float64),
+	}
+	for _, chunk := range chunks {
+		r.lengths = append(r.lengths, float64(len(chunk)))
+		r.total += float64(len(chunk))
+	}
+	return r
+}
+
+// Adds the item found in the given chunk, returns the item merged into and
+// whether it was found before
+func (r *ranking[T]) add(chunk int, key string, item T, relevance Float) (*T, bool) {
+	r.weights[key] += r.lengths[chunk]
+	relevance *= Float(r.lengths[chunk] / r.total)
+	if ranked, ok := r.byKey[key]; ok {
+		ranked.relevance += relevance
+		return &ranked.item, true
+	}
+
+	ranked := &rankedItem[T]{item: item, relevance: relevance}
+	r.byKey[key] = ranked
+	r.items = append(r.items, ranked)
+	return &ranked.item, false
+}
+
+// The items by descending relevance, at most maxRetrieve of the options
+func (r *ranking[T]) ranked(options url.Values) []*rankedItem[T] {
+	sort.SliceStable(r.items, func(i, j int) bool {
+		return r.items[i].relevance > r.items[j].relevance
+	})
+	if max, err := strconv.Atoi(options.Get("maxRetrieve")); err == nil && max > 0 && len(r.items) > max {
+		return r.items[:max]
+	}
+	return r.items
+}
+
+// Averages two sentiments by weight, mixed when they disagree
+func mergeSentiment(a Sentiment, weightA float64, b Sentiment, weightB float64) Sentiment {
+	if a.Type == "" || weightA == 0 {
+		return b
+	}
+	if b.Type == "" || weightB == 0 {
+		return a
+	}
+
+	merged := Sentiment{Score: Float((float64(a.Score)*weightA + float64(b.Score)*weightB) / (weightA + weightB))}
+	switch {
+	case merged.Score > 0:
+		merged.Type = "positive"
+	case merged.Score < 0:
+		merged.Type = "negative"
+	default:
+		merged.Type = "neutral"
+	}
+	if a.Mixed != 0 || b.Mixed != 0 || (a.Type != b.Type && a.Type != "neutral" && b.Type != "neutral") {
+		merged.Mixed = 1
+	}
+	return merged
+}
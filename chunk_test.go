@@ -0,0 +1,126 @@
+package alchemyapi
+
+import (
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestSplitText(t *testing.T) {
+	text := "# Title\n\nFirst sentence. Second one!\n\nAnother paragraph here.\n- item\n- item"
+	chunks := splitText(text, 30)
+	if strings.Join(chunks, "") != text {
+		t.Errorf("the chunks should add up to the text, but %q", chunks)
+	}
+	for _, chunk := range chunks {
+		if len(chunk) > 30 {
+			t.Errorf("chunk over the limit %q", chunk)
+		}
+	}
+	if chunks[0] != "# Title\n\n" || chunks[1] != "First sentence. Second one!\n\n" {
+		t.Errorf("should split at paragraphs, but %q", chunks)
+	}
+
+	chunks = splitText(strings.Repeat("é", 10), 5)
+	for _, chunk := range chunks {
+		if !strings.HasPrefix(chunk, "é") || len(chunk) > 5 {
+			t.Errorf("should not cut runes, but %q", chunks)
+		}
+	}
+
+	if chunks := splitText("short", 30); len(chunks) != 1 {
+		t.Errorf("want %v, but %v", 1, len(chunks))
+	}
+}
+
+func TestAnalyzerChunking(t *testing.T) {
+	apiKey := "foooooooooooooooooooooooooooooooooooobar"
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		text := r.PostForm.Get("text")
+		if len(text) > 40 {
+			t.Errorf("chunk over the limit %q", text)
+		}
+		switch {
+		case strings.Contains(r.URL.Path, "Sentiment") && strings.HasPrefix(text, "good"):
+			w.Write([]byte(`{"status":"OK","language":"english","totalTransactions":"1","docSentiment":{"type":"positive","score":"0.8"}}`))
+		case strings.Contains(r.URL.Path, "Sentiment"):
+			w.Write([]byte(`{"status":"OK","language":"english","totalTransactions":"1","docSentiment":{"type":"negative","score":"-0.4"}}`))
+		case strings.HasPrefix(text, "good"):
+			w.Write([]byte(`{"status":"OK","totalTransactions":"1","entities":[{"type":"Person","text":"Ada","count":"2","relevance":"0.9"},{"type":"City","text":"Paris","count":"1","relevance":"0.5"}]}`))
+		default:
+			w.Write([]byte(`{"status":"OK","totalTransactions":"1","entities":[{"type":"City","text":"paris","count":"3","relevance":"0.9"}]}`))
+		}
+	}
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	analyzer, _ := NewAnalyzer(apiKey, WithBaseURL(server.URL), WithChunking(ChunkOptions{MaxBytes: 40}))
+	// two chunks of the same length
+	text := "good news and more good news, really.\n\nbad news and even more bad news, sadly."
+
+	sentiment, err := analyzer.Sentiment("text", text, url.Values{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc := sentiment.DocSentiment
+	if doc.Type != "positive" || doc.Mixed != 1 || doc.Score < 0.19 || doc.Score > 0.21 {
+		t.Errorf("unexpected sentiment %v", doc)
+	}
+	if sentiment.TotalTransactions != 2 || sentiment.Language != "english" {
+		t.Errorf("unexpected response %v", sentiment)
+	}
+
+	entities, err := analyzer.Entities("text", text, url.Values{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entities.Entities) != 2 || entities.Entities[0].Text != "Paris" || entities.Entities[0].Count != 4 {
+		t.Errorf("unexpected entities %v", entities.Entities)
+	}
+	if math.Abs(float64(entities.Entities[0].Relevance)-0.7) > 1e-9 || math.Abs(float64(entities.Entities[1].Relevance)-0.45) > 1e-9 {
+		t.Errorf("unexpected relevance %v", entities.Entities)
+	}
+
+	entities, _ = analyzer.Entities("text", text, url.Values{"maxRetrieve": {"1"}})
+	if len(entities.Entities) != 1 {
+		t.Errorf("want %v, but %v", 1, len(entities.Entities))
+	}
+}
+
+func TestHTMLText(t *testing.T) {
+	document := "<html><head><style>p { color: red }</style><script>var a = 1;</script></head>\n" +
+		"<body><h1>Title</h1><!-- note --><p>First &amp; second\n line.</p><div>Next<br>line</div></body></html>"
+	want := "Title\n\nFirst & second line.\n\nNext\nline"
+	if got := htmlText(document); got != want {
+		t.Errorf("want %q, but %q", want, got)
+	}
+}
+
+func TestAnalyzerChunkingHTML(t *testing.T) {
+	apiKey := "foooooooooooooooooooooooooooooooooooobar"
+	var paths []string
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if text := r.PostForm.Get("text"); len(text) > 40 || strings.Contains(text, "<") {
+			t.Errorf("unexpected chunk %q", text)
+		}
+		paths = append(paths, r.URL.Path)
+		w.Write([]byte(`{"status":"OK","totalTransactions":"1","docSentiment":{"type":"positive","score":"0.5"}}`))
+	}
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	analyzer, _ := NewAnalyzer(apiKey, WithBaseURL(server.URL), WithChunking(ChunkOptions{MaxBytes: 40, Concurrency: 1}))
+	document := "<html><body><p>good news and more good news, really.</p><p>bad news and even more bad news, sadly.</p></body></html>"
+	sentiment, err := analyzer.Sentiment("html", document, url.Values{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sentiment.TotalTransactions != 2 || len(paths) != 2 || paths[0] != entryPoints["sentiment"]["text"] {
+		t.Errorf("want %v text calls, but %v", 2, paths)
+	}
+}
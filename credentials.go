@@ -0,0 +1,251 @@
+package alchemyapi
+
+/**
+  Copyright 2015 AlchemyAPI
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+      http://www.apache.org/licenses/LICENSE-2.0
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The environment variable the examples read the api key from
+const DefaultAPIKeyEnv = "ALCHEMY_API_KEY"
+
+// CredentialProvider supplies the api key, it is consulted on every
+// request and must be safe for concurrent use. Providers should implement
+// fmt.Stringer telling where the key comes from, without the key itself.
+type CredentialProvider interface {
+	APIKey(ctx context.Context) (string, error)
+}
+
+// KeyError is returned when a CredentialProvider supplies a malformed
+// key. It wraps ApiKeyInvalid and never holds the key itself.
+type KeyError struct {
+	Source string // the provider, e.g. "env ALCHEMY_API_KEY"
+	Reason string
+}
+
+func (e *KeyError) Error() string {
+	return fmt.Sprintf("malformed api key from %s: %s", e.Source, e.Reason)
+}
+
+func (e *KeyError) Unwrap() error {
+	return ApiKeyInvalid
+}
+
+// Takes the api key of every request from the provider instead of the key
+// passed to NewAnalyzer, which may be empty. A KeyPool, if any, takes
+// precedence.
+func WithCredentials(provider CredentialProvider) Option {
+	return func(analyzer *Analyzer) {
+		analyzer.credentials = provider
+	}
+}
+
+// The key to send the request with: the one set by an interceptor, the
+// one of the credential provider or the one passed to NewAnalyzer
+func (analyzer *Analyzer) resolveAPIKey(ctx context.Context, req *request) (string, error) {
+	if req.apiKey != "" {
+		return req.apiKey, nil
+	}
+	if analyzer.credentials == nil {
+		return analyzer.apiKey, nil
+	}
+
+	key, err := analyzer.credentials.APIKey(ctx)
+	if err != nil {
+		return "", err
+	}
+	if err := checkAPIKey(analyzer.credentials, key); err != nil {
+		return "", err
+	}
+	return key, nil
+}
+
+func checkAPIKey(provider CredentialProvider, key string) error {
+	reason := ""
+	switch {
+	case key == "":
+		reason = "empty"
+	case len(key) != 40:
+		reason = fmt.Sprintf("%d characters, want 40", len(key))
+	case strings.IndexFunc(key, func(r rune) bool { return !isAlphanumeric(r) }) >= 0:
+		reason = "unexpected characters"
+	default:
+		return nil
+	}
+	return &KeyError{Source: providerName(provider), Reason: reason}
+}
+
+// Where the key comes from: the String of the provider, or its type as
+// printing its fields might print the key
+func providerName(provider CredentialProvider) string {
+	if stringer, ok := provider.(fmt.Stringer); ok {
+		return stringer.String()
+	}
+	return fmt.Sprintf("%T", provider)
+}
+
+func isAlphanumeric(r rune) bool {
+	return r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z'
+}
+
+type staticCredentials string
+
+// The given key, for keys loaded once by the caller.
+func StaticCredentials(key string) CredentialProvider {
+	return staticCredentials(key)
+}
+
+func (key staticCredentials) APIKey(ctx context.Context) (string, error) {
+	return string(key), nil
+}
+
+func (key staticCredentials) String() string {
+	return "static credentials"
+}
+
+type envCredentials string
+
+// The value of the environment variable, DefaultAPIKeyEnv if name is
+// empty, read on every request.
+func EnvCredentials(name string) CredentialProvider {
+	if name == "" {
+		name = DefaultAPIKeyEnv
+	}
+	return envCredentials(name)
+}
+
+func (name envCredentials) APIKey(ctx context.Context) (string, error) {
+	return strings.TrimSpace(os.Getenv(string(name))), nil
+}
+
+func (name envCredentials) String() string {
+	return "env " + string(name)
+}
+
+// The content of a file, e.g. a secret mounted by the orchestrator
+type fileCredentials struct {
+	path    string
+	mu      sync.Mutex
+	key     string
+	modTime time.Time
+	size    int64
+}
+
+// The content of the file, surrounding whitespace trimmed. The file is
+// read again whenever its modification time or size changes, so rotated
+// secrets are picked up without a restart.
+func FileCredentials(path string) CredentialProvider {
+	return &fileCredentials{path: path}
+}
+
+func (f *fileCredentials) APIKey(ctx context.Context) (string, error) {
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return "", err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.key != "" && info.ModTime().Equal(f.modTime) && info.Size() == f.size {
+		return f.key, nil
+	}
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		return "", err
+	}
+	f.key, f.modTime, f.size = strings.TrimSpace(string(data)), info.ModTime(), info.Size()
+	return f.key, nil
+}
+
+func (f *fileCredentials) String() string {
+	return "file " + f.path
+}
+
+// How long a failed refresh of CachedCredentials is not retried, at most
+// the ttl
+const credentialsRetryDelay = 30 * time.Second
+
+type cachedCredentials struct {
+	provider   CredentialProvider
+	ttl        time.Duration
+	mu         sync.Mutex
+	key        string
+	expires    time.Time
+	refreshing chan struct{} // closed once the refresh in flight is over
+	now        func() time.Time
+}
+
+/*
+   Caches the key of the provider for ttl, for providers too slow or
+   costly to consult on every request, e.g. a remote secret store. When a
+   refresh fails or yields a malformed key, the previous key is kept and
+   the refresh retried after at most 30s; the error is only returned
+   without a previous key. Requests do not wait for a refresh when there
+   is a previous key.
+*/
+func CachedCredentials(provider CredentialProvider, ttl time.Duration) CredentialProvider {
+	return &cachedCredentials{provider: provider, ttl: ttl, now: time.Now}
+}
+
+func (c *cachedCredentials) APIKey(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	if c.key != "" && (c.refreshing != nil || c.now().Before(c.expires)) {
+		key := c.key
+		c.mu.Unlock()
+		return key, nil
+	}
+	if done := c.refreshing; done != nil {
+		// no previous key, wait for the refresh in flight
+		c.mu.Unlock()
+		select {
+		case <-done:
+			return c.APIKey(ctx)
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+	done := make(chan struct{})
+	c.refreshing = done
+	c.mu.Unlock()
+
+	key, err := c.provider.APIKey(ctx)
+	if err == nil {
+		err = checkAPIKey(c.provider, key)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.refreshing = nil
+	close(done)
+	now := c.now()
+	if err != nil {
+		if c.key != "" {
+			c.expires = now.Add(min(c.ttl, credentialsRetryDelay))
+			return c.key, nil
+		}
+		return "", err
+	}
+	c.key, c.expires = key, now.Add(c.ttl)
+	return key, nil
+}
+
+func (c *cachedCredentials) String() string {
+	return providerName(c.provider)
+}
@@ -0,0 +1,173 @@
+package alchemyapi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAnalyzerCredentials(t *testing.T) {
+	var sent string
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		sent = r.PostForm.Get("apikey")
+		w.Write([]byte("{\"status\":\"OK\"}"))
+	}
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	keyA, keyB := strings.Repeat("a", 40), strings.Repeat("b", 40)
+	path := filepath.Join(t.TempDir(), "apikey")
+	os.WriteFile(path, []byte(keyA+"\n"), 0600)
+
+	analyzer, err := NewAnalyzer("", WithBaseURL(server.URL), WithCredentials(FileCredentials(path)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := analyzer.Language("text", "foobar", url.Values{}); err != nil || sent != keyA {
+		t.Errorf("want %v, but %v, %v", keyA, sent, err)
+	}
+
+	// rotated without restart
+	os.WriteFile(path, []byte(keyB), 0600)
+	os.Chtimes(path, time.Now(), time.Now().Add(time.Minute))
+	if _, err := analyzer.Language("text", "foobar", url.Values{}); err != nil || sent != keyB {
+		t.Errorf("want %v, but %v, %v", keyB, sent, err)
+	}
+
+	os.WriteFile(path, []byte("short"), 0600)
+	os.Chtimes(path, time.Now(), time.Now().Add(2*time.Minute))
+	sent = ""
+	_, err = analyzer.Language("text", "foobar", url.Values{})
+	var keyErr *KeyError
+	if !errors.As(err, &keyErr) || !errors.Is(err, ApiKeyInvalid) || sent != "" {
+		t.Fatalf("want %v, but %v", ApiKeyInvalid, err)
+	}
+	if keyErr.Source != "file "+path || strings.Contains(err.Error(), "short") || ErrorClass(err) != "invalid_api_key" {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	t.Setenv("ALCHEMY_TEST_KEY", keyB)
+	analyzer, _ = NewAnalyzer("", WithBaseURL(server.URL), WithCredentials(EnvCredentials("ALCHEMY_TEST_KEY")))
+	if _, err := analyzer.Language("text", "foobar", url.Values{}); err != nil || sent != keyB {
+		t.Errorf("want %v, but %v, %v", keyB, sent, err)
+	}
+
+	if _, err := NewAnalyzer(""); err != ApiKeyInvalid {
+		t.Errorf("want %v, but %v", ApiKeyInvalid, err)
+	}
+}
+
+type countingProvider struct {
+	calls int
+	key   string
+	err   error
+}
+
+func (p *countingProvider) APIKey(ctx context.Context) (string, error) {
+	p.calls++
+	return p.key, p.err
+}
+
+func TestCachedCredentials(t *testing.T) {
+	keyA, keyB := strings.Repeat("a", 40), strings.Repeat("b", 40)
+	provider := &countingProvider{key: keyA}
+	cached := CachedCredentials(provider, time.Minute).(*cachedCredentials)
+	now := time.Now()
+	cached.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	cached.APIKey(ctx)
+	if key, _ := cached.APIKey(ctx); key != keyA || provider.calls != 1 {
+		t.Errorf("want %v, but %v after %d calls", keyA, key, provider.calls)
+	}
+
+	now = now.Add(time.Minute)
+	provider.key = keyB
+	if key, _ := cached.APIKey(ctx); key != keyB || provider.calls != 2 {
+		t.Errorf("want %v, but %v after %d calls", keyB, key, provider.calls)
+	}
+
+	// failed refreshes keep the previous key
+	now = now.Add(time.Minute)
+	provider.err = errors.New("store unavailable")
+	if key, err := cached.APIKey(ctx); key != keyB || err != nil {
+		t.Errorf("want %v, but %v, %v", keyB, key, err)
+	}
+	if cached.APIKey(ctx); provider.calls != 3 {
+		t.Errorf("a failed refresh should not be retried at once, but %d calls", provider.calls)
+	}
+	now = now.Add(credentialsRetryDelay)
+	if cached.APIKey(ctx); provider.calls != 4 {
+		t.Errorf("want %v, but %v", 4, provider.calls)
+	}
+
+	fresh := CachedCredentials(&countingProvider{key: "malformed"}, time.Minute)
+	if _, err := fresh.APIKey(ctx); !errors.Is(err, ApiKeyInvalid) {
+		t.Errorf("want %v, but %v", ApiKeyInvalid, err)
+	}
+}
+
+type blockingProvider struct {
+	key     string
+	started chan struct{}
+	release chan struct{}
+}
+
+func (p *blockingProvider) APIKey(ctx context.Context) (string, error) {
+	p.started <- struct{}{}
+	<-p.release
+	return p.key, nil
+}
+
+func TestCachedCredentialsSlowRefresh(t *testing.T) {
+	keyA, keyB := strings.Repeat("a", 40), strings.Repeat("b", 40)
+	provider := &blockingProvider{key: keyB, started: make(chan struct{}, 1), release: make(chan struct{})}
+	cached := CachedCredentials(provider, time.Minute).(*cachedCredentials)
+	cached.key = keyA
+
+	refreshed := make(chan string)
+	go func() {
+		key, _ := cached.APIKey(context.Background())
+		refreshed <- key
+	}()
+	<-provider.started
+
+	// the refresh in flight does not hold up the other requests
+	if key, err := cached.APIKey(context.Background()); key != keyA || err != nil {
+		t.Errorf("want %v, but %v, %v", keyA, key, err)
+	}
+	close(provider.release)
+	if key := <-refreshed; key != keyB {
+		t.Errorf("want %v, but %v", keyB, key)
+	}
+	if key, _ := cached.APIKey(context.Background()); key != keyB {
+		t.Errorf("want %v, but %v", keyB, key)
+	}
+}
+
+func TestKeyErrorHidesKey(t *testing.T) {
+	secret := "sk-live-secret-key-with-bad-length"
+	analyzer, _ := NewAnalyzer("", WithCredentials(&countingProvider{key: secret}))
+
+	_, err := analyzer.Language("text", "foobar", url.Values{})
+	var keyErr *KeyError
+	if !errors.As(err, &keyErr) || strings.Contains(err.Error(), secret) {
+		t.Fatalf("the error should not hold the key, but %v", err)
+	}
+	if keyErr.Source != "*alchemyapi.countingProvider" {
+		t.Errorf("want %v, but %v", "*alchemyapi.countingProvider", keyErr.Source)
+	}
+
+	cached := CachedCredentials(&countingProvider{key: secret}, time.Minute)
+	if _, err := cached.APIKey(context.Background()); err == nil || strings.Contains(err.Error(), secret) {
+		t.Errorf("the error should not hold the key, but %v", err)
+	}
+}
@@ -0,0 +1,139 @@
+package alchemyapi
+
+/**
+  Copyright 2015 AlchemyAPI
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+      http://www.apache.org/licenses/LICENSE-2.0
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Error classifications, use errors.Is on an error returned by an Analyzer call.
+var (
+	ErrDailyLimitExceeded      = errors.New("daily transaction limit exceeded")
+	ErrInvalidAPIKey           = errors.New("invalid api key")
+	ErrUnsupportedTextLanguage = errors.New("unsupported text language")
+	ErrContentExceedsMaxLimit  = errors.New("content exceeds size limit")
+	ErrCannotRetrieve          = errors.New("cannot retrieve")
+	ErrUnsupportedFlavor       = errors.New("flavor not available")
+	ErrThrottled               = errors.New("throttled")
+)
+
+// statusInfo prefixes reported by AlchemyAPI and their classification
+var statusInfoKinds = []struct {
+	prefix string
+	kind   error
+}{
+	{"daily-transaction-limit-exceeded", ErrDailyLimitExceeded},
+	{"invalid-api-key", ErrInvalidAPIKey},
+	{"unsupported-text-language", ErrUnsupportedTextLanguage},
+	{"content-exceeds-size-limit", ErrContentExceedsMaxLimit},
+	{"cannot-retrieve", ErrCannotRetrieve},
+	{"rate-limit-exceeded", ErrThrottled},
+	{"server-busy", ErrThrottled},
+	{"too-many-requests", ErrThrottled},
+}
+
+// APIError is returned when AlchemyAPI refuses a call (status other than OK)
+// or when the call can not be made for the requested flavor.
+type APIError struct {
+	Endpoint   string // the entry point arrange, e.g. "sentiment"
+	Flavor     string // url, text, html or image
+	StatusCode int    // http status code, 0 when no request has been sent
+	StatusInfo string // the raw statusInfo of the response
+	Err        error  // one of the Err* classifications, nil if unknown
+}
+
+func (e *APIError) Error() string {
+	if e.StatusInfo != "" {
+		return e.StatusInfo
+	}
+	if e.Err != nil {
+		return fmt.Sprintf("%s for %s: %s", e.Endpoint, e.Flavor, e.Err)
+	}
+	return fmt.Sprintf("%s for %s failed", e.Endpoint, e.Flavor)
+}
+
+func (e *APIError) Unwrap() error {
+	return e.Err
+}
+
+// Builds the error for a response whose status is not OK
+func newAPIError(arrange, flavor string, statusCode int, statusInfo string) *APIError {
+	return &APIError{
+		Endpoint:   arrange,
+		Flavor:     flavor,
+		StatusCode: statusCode,
+		StatusInfo: statusInfo,
+		Err:        classifyStatusInfo(statusInfo),
+	}
+}
+
+// Builds the error for a flavor not registered in the entry points
+func unsupportedFlavor(arrange, flavor string) *APIError {
+	return &APIError{Endpoint: arrange, Flavor: flavor, Err: ErrUnsupportedFlavor}
+}
+
+// A short, stable name for the kind of error, suited as a metric label:
+// the Err* classifications, "api" for other refused calls, "budget",
+// "option", "transport", "canceled", "timeout" or "other". Empty for nil.
+func ErrorClass(err error) string {
+	if err == nil {
+		return ""
+	}
+
+	for _, v := range errorClasses {
+		if errors.Is(err, v.kind) {
+			return v.class
+		}
+	}
+
+	var apiErr *APIError
+	var transportErr *TransportError
+	switch {
+	case errors.As(err, &apiErr):
+		return "api"
+	case errors.As(err, &transportErr):
+		return "transport"
+	}
+	return "other"
+}
+
+var errorClasses = []struct {
+	kind  error
+	class string
+}{
+	{ErrDailyLimitExceeded, "daily_limit_exceeded"},
+	{ErrInvalidAPIKey, "invalid_api_key"},
+	{ApiKeyInvalid, "invalid_api_key"},
+	{ErrUnsupportedTextLanguage, "unsupported_text_language"},
+	{ErrContentExceedsMaxLimit, "content_exceeds_max_limit"},
+	{ErrCannotRetrieve, "cannot_retrieve"},
+	{ErrUnsupportedFlavor, "unsupported_flavor"},
+	{ErrThrottled, "throttled"},
+	{ErrBudgetExhausted, "budget"},
+	{ErrInvalidOption, "option"},
+	{context.Canceled, "canceled"},
+	{context.DeadlineExceeded, "timeout"},
+}
+
+func classifyStatusInfo(statusInfo string) error {
+	for _, v := range statusInfoKinds {
+		if strings.HasPrefix(statusInfo, v.prefix) {
+			return v.kind
+		}
+	}
+	return nil
+}
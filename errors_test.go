@@ -0,0 +1,86 @@
+package alchemyapi
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestClassifyStatusInfo(t *testing.T) {
+	cases := map[string]error{
+		"daily-transaction-limit-exceeded": ErrDailyLimitExceeded,
+		"invalid-api-key":                  ErrInvalidAPIKey,
+		"unsupported-text-language":        ErrUnsupportedTextLanguage,
+		"content-exceeds-size-limit":       ErrContentExceedsMaxLimit,
+		"cannot-retrieve:http-redirect":    ErrCannotRetrieve,
+		"malfunction":                      nil,
+	}
+
+	for statusInfo, want := range cases {
+		if got := classifyStatusInfo(statusInfo); got != want {
+			t.Errorf("%s want %v, but %v", statusInfo, want, got)
+		}
+	}
+}
+
+func TestAnalyzerAPIError(t *testing.T) {
+	apiKey := "foooooooooooooooooooooooooooooooooooobar"
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("{\"status\":\"ERROR\",\"statusInfo\":\"daily-transaction-limit-exceeded\"}"))
+	}
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	analyzer, _ := NewAnalyzer(apiKey, WithBaseURL(server.URL))
+	_, err := analyzer.Entities("text", "foobar", url.Values{})
+	if !errors.Is(err, ErrDailyLimitExceeded) {
+		t.Fatalf("want %v, but %v", ErrDailyLimitExceeded, err)
+	}
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatal("should be an *APIError")
+	}
+	if apiErr.Endpoint != "entities" || apiErr.Flavor != "text" {
+		t.Errorf("want entities/text, but %s/%s", apiErr.Endpoint, apiErr.Flavor)
+	}
+	if apiErr.StatusCode != http.StatusOK {
+		t.Errorf("want %d, but %d", http.StatusOK, apiErr.StatusCode)
+	}
+	if apiErr.Error() != "daily-transaction-limit-exceeded" {
+		t.Errorf("want %s, but %s", "daily-transaction-limit-exceeded", apiErr.Error())
+	}
+}
+
+func TestAnalyzerUnsupportedFlavor(t *testing.T) {
+	analyzer, _ := NewAnalyzer("foooooooooooooooooooooooooooooooooooobar")
+	_, err := analyzer.Face("text", "foobar", url.Values{})
+	if !errors.Is(err, ErrUnsupportedFlavor) {
+		t.Fatalf("want %v, but %v", ErrUnsupportedFlavor, err)
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode != 0 {
+		t.Errorf("want %d, but %d", 0, apiErr.StatusCode)
+	}
+}
+
+func TestErrorClass(t *testing.T) {
+	cases := map[string]error{
+		"":                     nil,
+		"daily_limit_exceeded": newAPIError("entities", "text", 200, "daily-transaction-limit-exceeded"),
+		"api":                  newAPIError("entities", "text", 200, "malfunction"),
+		"transport":            &RetryError{Attempts: 3, Err: &TransportError{StatusCode: 502}},
+		"budget":               &BudgetError{Limit: 1, Used: 1},
+		"option":               &OptionError{Endpoint: "entities", Option: "foo"},
+		"other":                errors.New("foo"),
+	}
+
+	for want, err := range cases {
+		if got := ErrorClass(err); got != want {
+			t.Errorf("%v want %s, but %s", err, want, got)
+		}
+	}
+}
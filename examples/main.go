@@ -17,7 +17,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/url"
-	"os"
 
 	ai "github.com/elvuel/alchemyapi_go"
 )
@@ -37,7 +36,7 @@ var (
 
 func init() {
 	var err error
-	analyzer, err = ai.NewAnalyzer(os.Getenv("ALCHEMY_API_KEY"))
+	analyzer, err = ai.NewAnalyzer("", ai.WithCredentials(ai.EnvCredentials(ai.DefaultAPIKeyEnv)))
 	if err != nil {
 		panic(err)
 	}
@@ -0,0 +1,145 @@
+package alchemyapi
+
+/**
+  Copyright 2015 AlchemyAPI
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+      http://www.apache.org/licenses/LICENSE-2.0
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+)
+
+// The default limit of an image posted with the image flavor
+const DefaultMaxImageSize = 10 << 20
+
+// ImageSizeError is returned, without sending anything, when an image is
+// larger than the limit set with WithMaxImageSize. It wraps
+// ErrContentExceedsMaxLimit.
+type ImageSizeError struct {
+	Size  int64 // -1 when the image was read from a reader and not read to the end
+	Limit int64
+}
+
+func (e *ImageSizeError) Error() string {
+	if e.Size < 0 {
+		return fmt.Sprintf("%s: image larger than %d bytes", ErrContentExceedsMaxLimit, e.Limit)
+	}
+	return fmt.Sprintf("%s: image of %d bytes, limit %d", ErrContentExceedsMaxLimit, e.Size, e.Limit)
+}
+
+func (e *ImageSizeError) Unwrap() error {
+	return ErrContentExceedsMaxLimit
+}
+
+// Limits the size of the images posted with the image flavor
+// (DefaultMaxImageSize). Zero or less disables the limit.
+func WithMaxImageSize(max int64) Option {
+	return func(analyzer *Analyzer) {
+		analyzer.maxImageSize = max
+	}
+}
+
+/*
+   FaceFromReader detects faces in the image read from r, e.g. an upload
+   or the body of an object store download, without touching the disk.
+   Reading stops with an *ImageSizeError once the image exceeds the limit
+   set with WithMaxImageSize. The caller closes r.
+
+   For the options, see Face.
+*/
+func (analyzer *Analyzer) FaceFromReader(ctx context.Context, r io.Reader, options url.Values) (*FaceResponse, error) {
+	imageData, err := analyzer.readImage(r)
+	if err != nil {
+		return nil, err
+	}
+	return analyzer.FaceFromBytes(ctx, imageData, options)
+}
+
+// FaceFromBytes detects faces in the given image, see FaceFromReader.
+func (analyzer *Analyzer) FaceFromBytes(ctx context.Context, imageData []byte, options url.Values) (*FaceResponse, error) {
+	if err := analyzer.checkImageSize(int64(len(imageData))); err != nil {
+		return nil, err
+	}
+
+	req := newRequest("face", "image", options)
+	req.binData = imageData
+	req.values.Set("imagePostMode", "raw")
+	return do(ctx, analyzer, faceEndpoint, req)
+}
+
+/*
+   ImageTagFromReader tags the image read from r, see FaceFromReader.
+
+   For the options, see ImageTag.
+*/
+func (analyzer *Analyzer) ImageTagFromReader(ctx context.Context, r io.Reader, options url.Values) (*ImageTagResponse, error) {
+	imageData, err := analyzer.readImage(r)
+	if err != nil {
+		return nil, err
+	}
+	return analyzer.ImageTagFromBytes(ctx, imageData, options)
+}
+
+// ImageTagFromBytes tags the given image, see FaceFromReader.
+func (analyzer *Analyzer) ImageTagFromBytes(ctx context.Context, imageData []byte, options url.Values) (*ImageTagResponse, error) {
+	if err := analyzer.checkImageSize(int64(len(imageData))); err != nil {
+		return nil, err
+	}
+
+	req := newRequest("image_tag", "image", options)
+	req.binData = imageData
+	req.values.Set("imagePostMode", "raw")
+	return do(ctx, analyzer, imageTagEndpoint, req)
+}
+
+// Reads the image at path, refusing files over the limit before reading
+func (analyzer *Analyzer) readImageFile(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	if stat, err := f.Stat(); err == nil && stat.Mode().IsRegular() {
+		if err := analyzer.checkImageSize(stat.Size()); err != nil {
+			return nil, err
+		}
+	}
+	return analyzer.readImage(f)
+}
+
+// Reads at most one byte over the limit, so an oversized stream is never
+// buffered as a whole
+func (analyzer *Analyzer) readImage(r io.Reader) ([]byte, error) {
+	if analyzer.maxImageSize <= 0 {
+		return io.ReadAll(r)
+	}
+
+	imageData, err := io.ReadAll(io.LimitReader(r, analyzer.maxImageSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(imageData)) > analyzer.maxImageSize {
+		return nil, &ImageSizeError{Size: -1, Limit: analyzer.maxImageSize}
+	}
+	return imageData, nil
+}
+
+func (analyzer *Analyzer) checkImageSize(size int64) error {
+	if analyzer.maxImageSize > 0 && size > analyzer.maxImageSize {
+		return &ImageSizeError{Size: size, Limit: analyzer.maxImageSize}
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package alchemyapi
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAnalyzerImageFromReader(t *testing.T) {
+	apiKey := "foooooooooooooooooooooooooooooooooooobar"
+	var requests int
+	var received []byte
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		received, _ = io.ReadAll(r.Body)
+		if r.URL.Query().Get("imagePostMode") != "raw" {
+			t.Errorf("want %v, but %v", "raw", r.URL.Query().Get("imagePostMode"))
+		}
+		w.Write([]byte("{\"status\":\"OK\",\"imageFaces\":[{\"positionX\":\"1\"}],\"imageKeywords\":[{\"text\":\"cat\"}]}"))
+	}
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	analyzer, _ := NewAnalyzer(apiKey, WithBaseURL(server.URL), WithMaxImageSize(8))
+	ctx := context.Background()
+
+	faces, err := analyzer.FaceFromReader(ctx, strings.NewReader("12345678"), url.Values{})
+	if err != nil || len(faces.ImageFaces) != 1 || string(received) != "12345678" {
+		t.Errorf("unexpected response %v, %v, %s", faces, err, received)
+	}
+	tags, err := analyzer.ImageTagFromBytes(ctx, []byte("1234"), url.Values{})
+	if err != nil || len(tags.ImageKeywords) != 1 || string(received) != "1234" {
+		t.Errorf("unexpected response %v, %v, %s", tags, err, received)
+	}
+
+	var sizeErr *ImageSizeError
+	_, err = analyzer.ImageTagFromReader(ctx, strings.NewReader("123456789"), url.Values{})
+	if !errors.As(err, &sizeErr) || !errors.Is(err, ErrContentExceedsMaxLimit) {
+		t.Errorf("want %v, but %v", ErrContentExceedsMaxLimit, err)
+	}
+	_, err = analyzer.FaceFromBytes(ctx, bytes.Repeat([]byte("1"), 9), url.Values{})
+	if !errors.As(err, &sizeErr) || sizeErr.Size != 9 || sizeErr.Limit != 8 {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "image.jpg")
+	os.WriteFile(path, []byte("123456789"), 0644)
+	if _, err = analyzer.Face("image", path, url.Values{}); !errors.As(err, &sizeErr) {
+		t.Errorf("want %v, but %v", ErrContentExceedsMaxLimit, err)
+	}
+
+	if requests != 2 {
+		t.Errorf("oversized images should not be sent, but %d requests", requests)
+	}
+}
@@ -0,0 +1,182 @@
+package alchemyapi
+
+/**
+  Copyright 2015 AlchemyAPI
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+      http://www.apache.org/licenses/LICENSE-2.0
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Call is an AlchemyAPI request as seen by an Interceptor, which may
+// modify it before invoking the next one.
+type Call struct {
+	Endpoint string      // the arrange, e.g. "entities", read only
+	Flavor   string      // read only
+	Values   url.Values  // the options and the payload, without the api key
+	Image    []byte      // the image for the image flavor, nil otherwise
+	Header   http.Header // added to the http request
+	APIKey   string      // the key sent, the one of the Analyzer if empty
+}
+
+// Invoker sends a call, returning the JSON answer of AlchemyAPI.
+type Invoker func(ctx context.Context, call *Call) ([]byte, error)
+
+/*
+   Interceptor wraps the invocation of every call of an Analyzer, the way
+   an http.RoundTripper wraps http requests, but at the level of AlchemyAPI
+   calls. It may change the call before invoking next, change the answer or
+   error afterwards, or answer by itself without invoking next at all.
+
+   Interceptors run before everything else, so the cache, the logs and the
+   observers see the call as modified. Retries happen within next.
+*/
+type Interceptor interface {
+	Intercept(ctx context.Context, call *Call, next Invoker) ([]byte, error)
+}
+
+// InterceptorFunc is an Interceptor as a plain function.
+type InterceptorFunc func(ctx context.Context, call *Call, next Invoker) ([]byte, error)
+
+func (f InterceptorFunc) Intercept(ctx context.Context, call *Call, next Invoker) ([]byte, error) {
+	return f(ctx, call, next)
+}
+
+// Runs every call through the interceptors, the first one given being the
+// outermost. Repeated use appends to the chain.
+func WithInterceptor(interceptors ...Interceptor) Option {
+	return func(analyzer *Analyzer) {
+		analyzer.interceptors = append(analyzer.interceptors, interceptors...)
+	}
+}
+
+// Runs the request through the interceptors, then invokes it
+func (analyzer *Analyzer) analyze(ctx context.Context, req *request) ([]byte, error) {
+	if len(analyzer.interceptors) == 0 {
+		return analyzer.invoke(ctx, req)
+	}
+
+	invoker := func(ctx context.Context, call *Call) ([]byte, error) {
+		return analyzer.invoke(ctx, &request{
+			arrange: req.arrange,
+			flavor:  req.flavor,
+			values:  call.Values,
+			binData: call.Image,
+			scale:   req.scale,
+			header:  call.Header,
+			apiKey:  call.APIKey,
+		})
+	}
+	for i := len(analyzer.interceptors) - 1; i >= 0; i-- {
+		interceptor, next := analyzer.interceptors[i], invoker
+		invoker = func(ctx context.Context, call *Call) ([]byte, error) {
+			return interceptor.Intercept(ctx, call, next)
+		}
+	}
+
+	return invoker(ctx, &Call{
+		Endpoint: req.arrange,
+		Flavor:   req.flavor,
+		Values:   copyValues(req.values),
+		Image:    req.binData,
+		Header:   make(http.Header),
+	})
+}
+
+// Adds the headers to every http request, e.g. the credentials of an
+// outbound gateway.
+func HeaderInterceptor(header http.Header) Interceptor {
+	return InterceptorFunc(func(ctx context.Context, call *Call, next Invoker) ([]byte, error) {
+		for k, v := range header {
+			call.Header[k] = append(call.Header[k], v...)
+		}
+		return next(ctx, call)
+	})
+}
+
+// PIIPattern is a kind of personal data to redact.
+type PIIPattern struct {
+	Name        string
+	Regexp      *regexp.Regexp
+	Replacement string
+	Valid       func(match string) bool // optional check of the matches, e.g. a checksum
+}
+
+// The patterns RedactPII uses by default: emails, payment card numbers,
+// US social security numbers, phone numbers and IPv4 addresses.
+var DefaultPIIPatterns = []PIIPattern{
+	{Name: "email", Regexp: regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`), Replacement: "[EMAIL]"},
+	{Name: "card", Regexp: regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`), Replacement: "[CARD]", Valid: luhn},
+	{Name: "ssn", Regexp: regexp.MustCompile(`\b\d{3}-\d{2}-\d{4}\b`), Replacement: "[SSN]"},
+	{Name: "phone", Regexp: regexp.MustCompile(`(?:\+\d{1,3}[ .-]?)?(?:\(\d{3}\)|\b\d{3})[ .-]?\d{3}[ .-]?\d{4}\b`), Replacement: "[PHONE]"},
+	{Name: "ip", Regexp: regexp.MustCompile(`\b(?:\d{1,3}\.){3}\d{1,3}\b`), Replacement: "[IP]"},
+}
+
+/*
+   Replaces personal data in the text and html payloads before they leave
+   the process, applying the patterns in order (DefaultPIIPatterns if none
+   are given). Urls are sent as is, AlchemyAPI fetches their content
+   itself.
+*/
+func RedactPII(patterns ...PIIPattern) Interceptor {
+	if len(patterns) == 0 {
+		patterns = DefaultPIIPatterns
+	}
+	return InterceptorFunc(func(ctx context.Context, call *Call, next Invoker) ([]byte, error) {
+		for _, key := range []string{"text", "html"} {
+			values := call.Values[key]
+			for i, value := range values {
+				values[i] = redact(value, patterns)
+			}
+		}
+		return next(ctx, call)
+	})
+}
+
+func redact(s string, patterns []PIIPattern) string {
+	for _, pattern := range patterns {
+		s = pattern.Regexp.ReplaceAllStringFunc(s, func(match string) string {
+			if pattern.Valid != nil && !pattern.Valid(match) {
+				return match
+			}
+			return pattern.Replacement
+		})
+	}
+	return s
+}
+
+// Reports whether the digits of s pass the Luhn checksum
+func luhn(s string) bool {
+	digits := strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, s)
+
+	sum := 0
+	for i := 0; i < len(digits); i++ {
+		d := int(digits[len(digits)-1-i] - '0')
+		if i%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+	return len(digits) > 0 && sum%10 == 0
+}
@@ -0,0 +1,85 @@
+package alchemyapi
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestAnalyzerInterceptors(t *testing.T) {
+	apiKey := "foooooooooooooooooooooooooooooooooooobar"
+	var requests int
+	var form url.Values
+	var header http.Header
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		r.ParseForm()
+		form, header = r.PostForm, r.Header
+		w.Write([]byte("{\"status\":\"OK\",\"language\":\"english\"}"))
+	}
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	var order []string
+	trace := func(name string) Interceptor {
+		return InterceptorFunc(func(ctx context.Context, call *Call, next Invoker) ([]byte, error) {
+			order = append(order, name+":"+call.Endpoint+"/"+call.Flavor)
+			return next(ctx, call)
+		})
+	}
+	secret := InterceptorFunc(func(ctx context.Context, call *Call, next Invoker) ([]byte, error) {
+		call.APIKey = "secreeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeet"
+		call.Values.Set("text", call.Values.Get("text")+" signed")
+		return next(ctx, call)
+	})
+
+	analyzer, _ := NewAnalyzer(apiKey, WithBaseURL(server.URL),
+		WithInterceptor(trace("first"), trace("second")),
+		WithInterceptor(HeaderInterceptor(http.Header{"X-Gateway": {"token"}}), secret),
+	)
+	options := url.Values{"sentiment": {"1"}}
+	if _, err := analyzer.Language("text", "foobar", options); err != nil {
+		t.Fatal(err)
+	}
+	if len(order) != 2 || order[0] != "first:language/text" || order[1] != "second:language/text" {
+		t.Errorf("unexpected order %v", order)
+	}
+	if header.Get("X-Gateway") != "token" || form.Get("apikey") != "secreeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeet" {
+		t.Errorf("unexpected request %v %v", header, form)
+	}
+	if form.Get("text") != "foobar signed" || len(options) != 1 {
+		t.Errorf("unexpected form %v", form)
+	}
+
+	// answering without sending
+	canned := InterceptorFunc(func(ctx context.Context, call *Call, next Invoker) ([]byte, error) {
+		return []byte("{\"status\":\"OK\",\"language\":\"latin\"}"), nil
+	})
+	analyzer, _ = NewAnalyzer(apiKey, WithBaseURL(server.URL), WithInterceptor(canned))
+	response, err := analyzer.Language("text", "foobar", url.Values{})
+	if err != nil || response.Language != "latin" || requests != 1 {
+		t.Errorf("unexpected response %v, %v", response, err)
+	}
+}
+
+func TestRedactPII(t *testing.T) {
+	var sent string
+	invoker := func(ctx context.Context, call *Call) ([]byte, error) {
+		sent = call.Values.Get("text")
+		return nil, nil
+	}
+	text := "Mail jane.doe@example.com or call (555) 123-4567, card 4111 1111 1111 1111, " +
+		"ssn 123-45-6789, from 10.0.0.1, order 1234567890123456"
+	call := &Call{Values: url.Values{"text": {text}, "url": {"http://a@b.com"}}}
+	RedactPII().Intercept(context.Background(), call, invoker)
+
+	want := "Mail [EMAIL] or call [PHONE], card [CARD], ssn [SSN], from [IP], order 1234567890123456"
+	if sent != want {
+		t.Errorf("want %v, but %v", want, sent)
+	}
+	if call.Values.Get("url") != "http://a@b.com" {
+		t.Error("urls should be left untouched")
+	}
+}
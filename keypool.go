@@ -0,0 +1,200 @@
+package alchemyapi
+
+/**
+  Copyright 2015 AlchemyAPI
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+      http://www.apache.org/licenses/LICENSE-2.0
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// KeySelection tells which key of a KeyPool a request is sent with.
+type KeySelection int
+
+const (
+	RoundRobin KeySelection = iota // the keys in turn
+	LeastUsed                      // the key with the fewest transactions since the reset
+)
+
+// KeyUsage is a snapshot of the use of a key of a KeyPool since the last
+// reset.
+type KeyUsage struct {
+	Index        int    // position of the key in the pool
+	Key          string // the last 4 characters of the key, for telling keys apart in reports
+	Requests     int64
+	Transactions int64
+	Failures     int64 // requests which failed, daily limit hits included
+	Benched      bool  // the daily limit was hit, unused until ResetAt
+	ResetAt      time.Time
+}
+
+/*
+   KeyPool spreads the requests of one or more analyzers over several API
+   keys with separate daily quotas. A key answered with
+   daily-transaction-limit-exceeded is benched until the next reset of the
+   schedule and the request is sent again with the next key; the request
+   only fails once every key is benched.
+
+	pool, err := NewKeyPool(keys, LeastUsed, nil)
+	analyzer, err := NewAnalyzer("", WithKeyPool(pool))
+*/
+type KeyPool struct {
+	mu        sync.Mutex
+	keys      []*pooledKey
+	selection KeySelection
+	schedule  ResetSchedule
+	resetAt   time.Time
+	next      int
+	now       func() time.Time
+}
+
+type pooledKey struct {
+	key          string
+	requests     int64
+	transactions int64
+	failures     int64
+	benched      bool
+}
+
+// Creates new KeyPool, a nil schedule resets daily at midnight UTC. Every
+// key must be a valid api key.
+func NewKeyPool(keys []string, selection KeySelection, schedule ResetSchedule) (*KeyPool, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("key pool without keys")
+	}
+	if schedule == nil {
+		schedule = ResetDaily(nil)
+	}
+
+	pool := &KeyPool{selection: selection, schedule: schedule, now: time.Now}
+	for _, key := range keys {
+		if len(key) != 40 {
+			return nil, ApiKeyInvalid
+		}
+		pool.keys = append(pool.keys, &pooledKey{key: key})
+	}
+	return pool, nil
+}
+
+// Draws the api key of every request from the pool instead of using the
+// key passed to NewAnalyzer, which may be empty.
+func WithKeyPool(pool *KeyPool) Option {
+	return func(analyzer *Analyzer) {
+		analyzer.keyPool = pool
+	}
+}
+
+// Usage of every key, in pool order.
+func (pool *KeyPool) Usage() []KeyUsage {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	pool.rollover()
+	usage := make([]KeyUsage, len(pool.keys))
+	for i, k := range pool.keys {
+		usage[i] = KeyUsage{
+			Index:        i,
+			Key:          k.key[len(k.key)-4:],
+			Requests:     k.requests,
+			Transactions: k.transactions,
+			Failures:     k.failures,
+			Benched:      k.benched,
+			ResetAt:      pool.resetAt,
+		}
+	}
+	return usage
+}
+
+// Sends with one key after the other until a key is not over its daily
+// limit. send returns the answer, the transactions charged and the error.
+func (pool *KeyPool) do(send func(apiKey string) ([]byte, int64, error)) ([]byte, error) {
+	tried := make(map[*pooledKey]bool)
+	var lastErr error
+	for {
+		k, err := pool.acquire(tried)
+		if err != nil {
+			if lastErr != nil {
+				return nil, lastErr
+			}
+			return nil, err
+		}
+
+		data, transactions, err := send(k.key)
+		pool.release(k, transactions, err)
+		if !errors.Is(err, ErrDailyLimitExceeded) {
+			return data, err
+		}
+		tried[k] = true
+		lastErr = err
+	}
+}
+
+// Picks a key not benched and not tried yet
+func (pool *KeyPool) acquire(tried map[*pooledKey]bool) (*pooledKey, error) {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	pool.rollover()
+	var picked *pooledKey
+	for i := range pool.keys {
+		index := (pool.next + i) % len(pool.keys)
+		k := pool.keys[index]
+		if k.benched || tried[k] {
+			continue
+		}
+		if pool.selection == RoundRobin {
+			picked = k
+			pool.next = index + 1
+			break
+		}
+		if picked == nil || k.transactions < picked.transactions {
+			picked = k
+		}
+	}
+	if picked == nil {
+		return nil, fmt.Errorf("%w: every key of the pool is benched until %s",
+			ErrDailyLimitExceeded, pool.resetAt.Format(time.RFC3339))
+	}
+	picked.requests++
+	return picked, nil
+}
+
+func (pool *KeyPool) release(k *pooledKey, transactions int64, err error) {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	if err != nil {
+		k.failures++
+	} else {
+		k.transactions += transactions
+	}
+	if errors.Is(err, ErrDailyLimitExceeded) {
+		k.benched = true
+	}
+}
+
+// Starts over once the reset time passed
+func (pool *KeyPool) rollover() {
+	now := pool.now()
+	if !pool.resetAt.IsZero() && now.Before(pool.resetAt) {
+		return
+	}
+	if !pool.resetAt.IsZero() {
+		for _, k := range pool.keys {
+			*k = pooledKey{key: k.key}
+		}
+	}
+	pool.resetAt = pool.schedule.Next(now)
+}
@@ -0,0 +1,96 @@
+package alchemyapi
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestKeyPool(t *testing.T) {
+	keyA, keyB, keyC := strings.Repeat("a", 40), strings.Repeat("b", 40), strings.Repeat("c", 40)
+	limited := map[string]bool{keyA: true}
+	var sent []string
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		key := r.PostForm.Get("apikey")
+		sent = append(sent, key[:1])
+		if limited[key] {
+			w.Write([]byte("{\"status\":\"ERROR\",\"statusInfo\":\"daily-transaction-limit-exceeded\"}"))
+			return
+		}
+		w.Write([]byte("{\"status\":\"OK\",\"totalTransactions\":\"2\"}"))
+	}
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	if _, err := NewKeyPool([]string{keyA, "short"}, RoundRobin, nil); err != ApiKeyInvalid {
+		t.Errorf("want %v, but %v", ApiKeyInvalid, err)
+	}
+
+	pool, _ := NewKeyPool([]string{keyA, keyB, keyC}, RoundRobin, ResetEvery(time.Hour))
+	now := time.Date(2015, 6, 1, 12, 0, 0, 0, time.UTC)
+	pool.now = func() time.Time { return now }
+	analyzer, err := NewAnalyzer("", WithBaseURL(server.URL), WithKeyPool(pool))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, err := analyzer.Language("text", "foobar", url.Values{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// a is benched on the first call, which goes on with b
+	if strings.Join(sent, "") != "abcb" {
+		t.Errorf("want %v, but %v", "abcb", strings.Join(sent, ""))
+	}
+	usage := pool.Usage()
+	if !usage[0].Benched || usage[0].Failures != 1 || usage[1].Requests != 2 || usage[1].Transactions != 4 ||
+		usage[2].Transactions != 2 || usage[1].Key != "bbbb" {
+		t.Errorf("unexpected usage %+v", usage)
+	}
+
+	limited[keyB], limited[keyC] = true, true
+	_, err = analyzer.Language("text", "foobar", url.Values{})
+	var apiErr *APIError
+	if !errors.Is(err, ErrDailyLimitExceeded) || !errors.As(err, &apiErr) {
+		t.Errorf("want %v, but %v", ErrDailyLimitExceeded, err)
+	}
+	sent = nil
+	if _, err = analyzer.Language("text", "foobar", url.Values{}); !errors.Is(err, ErrDailyLimitExceeded) || len(sent) != 0 {
+		t.Errorf("should fail without sending once every key is benched, %v", err)
+	}
+
+	// benched keys come back after the reset
+	limited = map[string]bool{}
+	now = now.Add(time.Hour)
+	if _, err := analyzer.Language("text", "foobar", url.Values{}); err != nil {
+		t.Errorf("should not raise exception, %v", err)
+	}
+	for _, u := range pool.Usage() {
+		if u.Benched {
+			t.Errorf("unexpected usage %+v", u)
+		}
+	}
+}
+
+func TestKeyPoolLeastUsed(t *testing.T) {
+	keyA, keyB := strings.Repeat("a", 40), strings.Repeat("b", 40)
+	pool, _ := NewKeyPool([]string{keyA, keyB}, LeastUsed, nil)
+	send := func(transactions int64) func(string) ([]byte, int64, error) {
+		return func(string) ([]byte, int64, error) { return nil, transactions, nil }
+	}
+
+	pool.do(send(5))
+	pool.do(send(1))
+	pool.do(send(1))
+	pool.do(send(1))
+	usage := pool.Usage()
+	if usage[0].Transactions != 5 || usage[1].Transactions != 3 {
+		t.Errorf("unexpected usage %+v", usage)
+	}
+}
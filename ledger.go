@@ -0,0 +1,237 @@
+package alchemyapi
+
+/**
+  Copyright 2015 AlchemyAPI
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+      http://www.apache.org/licenses/LICENSE-2.0
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var ErrBudgetExhausted = errors.New("transaction budget exhausted")
+
+// BudgetError is returned, without sending anything, once the transaction
+// budget of the Ledger is used up.
+type BudgetError struct {
+	Limit   int64
+	Used    int64
+	ResetAt time.Time // zero when the budget never resets
+}
+
+func (e *BudgetError) Error() string {
+	if e.ResetAt.IsZero() {
+		return fmt.Sprintf("%s: %d of %d used", ErrBudgetExhausted, e.Used, e.Limit)
+	}
+	return fmt.Sprintf("%s: %d of %d used, resets at %s",
+		ErrBudgetExhausted, e.Used, e.Limit, e.ResetAt.Format(time.RFC3339))
+}
+
+func (e *BudgetError) Unwrap() error {
+	return ErrBudgetExhausted
+}
+
+// ResetSchedule tells when the budget of a Ledger starts over.
+type ResetSchedule interface {
+	// The first reset strictly after t
+	Next(t time.Time) time.Time
+}
+
+type dailyReset struct {
+	loc *time.Location
+}
+
+// Resets at midnight in the given location, UTC if nil.
+func ResetDaily(loc *time.Location) ResetSchedule {
+	if loc == nil {
+		loc = time.UTC
+	}
+	return dailyReset{loc: loc}
+}
+
+func (s dailyReset) Next(t time.Time) time.Time {
+	t = t.In(s.loc)
+	return time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, s.loc)
+}
+
+type intervalReset struct {
+	interval time.Duration
+}
+
+// Resets every interval, counted from the first use of the Ledger.
+func ResetEvery(interval time.Duration) ResetSchedule {
+	return intervalReset{interval: interval}
+}
+
+func (s intervalReset) Next(t time.Time) time.Time {
+	return t.Add(s.interval)
+}
+
+// LedgerKey identifies the entry point of the accounted transactions.
+type LedgerKey struct {
+	Endpoint string
+	Flavor   string
+}
+
+// Ledger accumulates the transactions reported by AlchemyAPI (the
+// totalTransactions field, 1 for responses without it) per endpoint and
+// flavor, and optionally enforces a budget which starts over on a schedule.
+// A Ledger may be shared by several analyzers, see WithLedger.
+type Ledger struct {
+	mu       sync.Mutex
+	limit    int64
+	schedule ResetSchedule
+	resetAt  time.Time
+	used     int64
+	usage    map[LedgerKey]int64
+	now      func() time.Time
+}
+
+// Creates new Ledger, a limit of 0 means no budget and a nil schedule a
+// budget which never resets.
+func NewLedger(limit int64, schedule ResetSchedule) *Ledger {
+	return &Ledger{
+		limit:    limit,
+		schedule: schedule,
+		usage:    make(map[LedgerKey]int64),
+		now:      time.Now,
+	}
+}
+
+// Accounts the transactions of the analyzer in the given ledger.
+func WithLedger(ledger *Ledger) Option {
+	return func(analyzer *Analyzer) {
+		if ledger != nil {
+			analyzer.ledger = ledger
+		}
+	}
+}
+
+// Fails calls fast with a *BudgetError once limit transactions have been
+// used since the last reset.
+func WithTransactionBudget(limit int64, schedule ResetSchedule) Option {
+	return WithLedger(NewLedger(limit, schedule))
+}
+
+// Returns the ledger the analyzer accounts its transactions in.
+func (analyzer *Analyzer) Ledger() *Ledger {
+	return analyzer.ledger
+}
+
+// Transactions per endpoint and flavor, not affected by the budget resets.
+func (ledger *Ledger) Usage() map[LedgerKey]int64 {
+	ledger.mu.Lock()
+	defer ledger.mu.Unlock()
+
+	usage := make(map[LedgerKey]int64, len(ledger.usage))
+	for k, v := range ledger.usage {
+		usage[k] = v
+	}
+	return usage
+}
+
+// Transactions of every endpoint and flavor.
+func (ledger *Ledger) Total() int64 {
+	ledger.mu.Lock()
+	defer ledger.mu.Unlock()
+
+	var total int64
+	for _, v := range ledger.usage {
+		total += v
+	}
+	return total
+}
+
+// Transactions used since the last budget reset.
+func (ledger *Ledger) Used() int64 {
+	ledger.mu.Lock()
+	defer ledger.mu.Unlock()
+
+	ledger.rollover()
+	return ledger.used
+}
+
+// Transactions left until the budget is exhausted, -1 without a budget.
+func (ledger *Ledger) Remaining() int64 {
+	ledger.mu.Lock()
+	defer ledger.mu.Unlock()
+
+	if ledger.limit <= 0 {
+		return -1
+	}
+	ledger.rollover()
+	if ledger.used >= ledger.limit {
+		return 0
+	}
+	return ledger.limit - ledger.used
+}
+
+// Clears the usage and restarts the budget.
+func (ledger *Ledger) Reset() {
+	ledger.mu.Lock()
+	defer ledger.mu.Unlock()
+
+	ledger.used = 0
+	ledger.usage = make(map[LedgerKey]int64)
+	ledger.resetAt = time.Time{}
+}
+
+// Fails when the budget is exhausted
+func (ledger *Ledger) check() error {
+	if ledger == nil {
+		return nil
+	}
+
+	ledger.mu.Lock()
+	defer ledger.mu.Unlock()
+
+	if ledger.limit <= 0 {
+		return nil
+	}
+	ledger.rollover()
+	if ledger.used >= ledger.limit {
+		return &BudgetError{Limit: ledger.limit, Used: ledger.used, ResetAt: ledger.resetAt}
+	}
+	return nil
+}
+
+func (ledger *Ledger) record(arrange, flavor string, transactions int64) {
+	if ledger == nil {
+		return
+	}
+
+	ledger.mu.Lock()
+	defer ledger.mu.Unlock()
+
+	ledger.rollover()
+	ledger.used += transactions
+	ledger.usage[LedgerKey{Endpoint: arrange, Flavor: flavor}] += transactions
+}
+
+// Starts the budget over once the reset is due, lock must be held
+func (ledger *Ledger) rollover() {
+	if ledger.schedule == nil {
+		return
+	}
+
+	now := ledger.now()
+	if ledger.resetAt.IsZero() {
+		ledger.resetAt = ledger.schedule.Next(now)
+		return
+	}
+	if !now.Before(ledger.resetAt) {
+		ledger.used = 0
+		ledger.resetAt = ledger.schedule.Next(now)
+	}
+}
@@ -0,0 +1,131 @@
+package alchemyapi
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLedgerBudgetReset(t *testing.T) {
+	now := time.Date(2015, 3, 1, 23, 0, 0, 0, time.UTC)
+	ledger := NewLedger(3, ResetDaily(time.UTC))
+	ledger.now = func() time.Time { return now }
+
+	ledger.record("sentiment", "text", 2)
+	if err := ledger.check(); err != nil {
+		t.Fatalf("should not be error, %v", err)
+	}
+
+	ledger.record("entities", "url", 1)
+	err := ledger.check()
+	if !errors.Is(err, ErrBudgetExhausted) {
+		t.Fatalf("want %v, but %v", ErrBudgetExhausted, err)
+	}
+	var budgetErr *BudgetError
+	if !errors.As(err, &budgetErr) || !budgetErr.ResetAt.Equal(time.Date(2015, 3, 2, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("should reset at midnight, but %v", err)
+	}
+	if got := ledger.Remaining(); got != 0 {
+		t.Errorf("want %d, but %d", 0, got)
+	}
+
+	now = now.Add(2 * time.Hour)
+	if err := ledger.check(); err != nil {
+		t.Errorf("should be reset, but %v", err)
+	}
+	if got := ledger.Used(); got != 0 {
+		t.Errorf("want %d, but %d", 0, got)
+	}
+
+	// the usage survives the budget resets
+	if got := ledger.Total(); got != 3 {
+		t.Errorf("want %d, but %d", 3, got)
+	}
+	if got := ledger.Usage()[LedgerKey{"sentiment", "text"}]; got != 2 {
+		t.Errorf("want %d, but %d", 2, got)
+	}
+}
+
+func TestResetEvery(t *testing.T) {
+	start := time.Date(2015, 3, 1, 10, 0, 0, 0, time.UTC)
+	if got := ResetEvery(time.Hour).Next(start); !got.Equal(start.Add(time.Hour)) {
+		t.Errorf("want %v, but %v", start.Add(time.Hour), got)
+	}
+}
+
+func TestAnalyzerTransactionBudget(t *testing.T) {
+	apiKey := "foooooooooooooooooooooooooooooooooooobar"
+	var calls int32
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		switch r.URL.Path {
+		case entryPoints["combined"]["text"]:
+			w.Write([]byte("{\"status\":\"OK\",\"totalTransactions\":\"3\"}"))
+		default:
+			w.Write([]byte("{\"status\":\"OK\"}"))
+		}
+	}
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	analyzer, _ := NewAnalyzer(apiKey, WithBaseURL(server.URL), WithTransactionBudget(4, nil))
+	if _, err := analyzer.Combined("text", "foobar", url.Values{}); err != nil {
+		t.Fatalf("should not raise exception, %v", err)
+	}
+	if _, err := analyzer.Language("text", "foobar", url.Values{}); err != nil {
+		t.Fatalf("should not raise exception, %v", err)
+	}
+
+	_, err := analyzer.Language("text", "foobar", url.Values{})
+	if !errors.Is(err, ErrBudgetExhausted) {
+		t.Fatalf("want %v, but %v", ErrBudgetExhausted, err)
+	}
+	if calls != 2 {
+		t.Errorf("exhausted budget should fail fast, want %d calls, but %d", 2, calls)
+	}
+
+	usage := analyzer.Ledger().Usage()
+	if usage[LedgerKey{"combined", "text"}] != 3 || usage[LedgerKey{"language", "text"}] != 1 {
+		t.Errorf("unexpected usage %v", usage)
+	}
+}
+
+func TestAnalyzerTransactionsFormats(t *testing.T) {
+	apiKey := "foooooooooooooooooooooooooooooooooooobar"
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case entryPoints["authors"]["url"]:
+			w.Write([]byte("{\"status\":\"OK\",\"totalTransactions\":\"\",\"authors\":{\"confident\":\"yes\",\"names\":[\"Ada\"]}}"))
+		case entryPoints["combined"]["text"]:
+			w.Write([]byte("{\"status\":\"OK\",\"totalTransactions\":\" 2\"}"))
+		default:
+			w.Write([]byte("{\"status\":\"OK\",\"totalTransactions\":5}"))
+		}
+	}
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	analyzer, _ := NewAnalyzer(apiKey, WithBaseURL(server.URL))
+	authors, err := analyzer.Authors("url", "http://example.com", url.Values{})
+	if err != nil || !authors.Authors.Confident {
+		t.Fatalf("should not raise exception, %v", err)
+	}
+	if _, err := analyzer.Combined("text", "foobar", url.Values{}); err != nil {
+		t.Fatalf("should not raise exception, %v", err)
+	}
+	if _, err := analyzer.Language("text", "foobar", url.Values{}); err != nil {
+		t.Fatalf("should not raise exception, %v", err)
+	}
+
+	usage := analyzer.Ledger().Usage()
+	want := map[LedgerKey]int64{{"authors", "url"}: 1, {"combined", "text"}: 2, {"language", "text"}: 5}
+	for k, v := range want {
+		if usage[k] != v {
+			t.Errorf("%v want %v, but %v", k, v, usage[k])
+		}
+	}
+}
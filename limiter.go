@@ -0,0 +1,197 @@
+package alchemyapi
+
+/**
+  Copyright 2015 AlchemyAPI
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+      http://www.apache.org/licenses/LICENSE-2.0
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Limits the requests per second (token bucket) and the requests in flight
+// (semaphore) of an Analyzer. Callers block until they are allowed to send.
+type limiter struct {
+	mu     sync.Mutex
+	rate   float64 // tokens per second, 0 means unlimited
+	burst  float64
+	tokens float64
+	last   time.Time
+
+	slots chan struct{} // nil means unlimited
+
+	stats LimiterStats
+}
+
+// LimiterStats is a snapshot of the time spent waiting on the limits
+// configured with WithRateLimit and WithMaxInFlight.
+type LimiterStats struct {
+	Requests  int64         // requests which went through the limiter
+	Waited    int64         // requests which had to wait
+	Waiting   int64         // requests currently waiting
+	InFlight  int64         // requests currently in flight
+	TotalWait time.Duration // total time spent waiting
+	MaxWait   time.Duration // longest single wait
+}
+
+// Average wait of the requests which went through the limiter
+func (stats LimiterStats) AverageWait() time.Duration {
+	if stats.Requests == 0 {
+		return 0
+	}
+	return stats.TotalWait / time.Duration(stats.Requests)
+}
+
+// Allows at most rps requests per second with bursts of burst requests,
+// shared by every goroutine using the Analyzer. Retries count as requests.
+func WithRateLimit(rps float64, burst int) Option {
+	return func(analyzer *Analyzer) {
+		if rps <= 0 {
+			return
+		}
+		if burst < 1 {
+			burst = 1
+		}
+		l := analyzer.ensureLimiter()
+		l.rate = rps
+		l.burst = float64(burst)
+		l.tokens = float64(burst)
+	}
+}
+
+// Allows at most n requests in flight at the same time.
+func WithMaxInFlight(n int) Option {
+	return func(analyzer *Analyzer) {
+		if n <= 0 {
+			return
+		}
+		analyzer.ensureLimiter().slots = make(chan struct{}, n)
+	}
+}
+
+// Returns the current wait statistics, zero when no limit is configured.
+func (analyzer *Analyzer) LimiterStats() LimiterStats {
+	if analyzer.limiter == nil {
+		return LimiterStats{}
+	}
+
+	analyzer.limiter.mu.Lock()
+	defer analyzer.limiter.mu.Unlock()
+	return analyzer.limiter.stats
+}
+
+func (analyzer *Analyzer) ensureLimiter() *limiter {
+	if analyzer.limiter == nil {
+		analyzer.limiter = &limiter{}
+	}
+	return analyzer.limiter
+}
+
+// Blocks until a slot and a token are available or the context is done.
+// The returned func releases the slot and must be called once the
+// request is over.
+func (l *limiter) acquire(ctx context.Context) (func(), error) {
+	if l == nil {
+		return func() {}, nil
+	}
+
+	start := time.Now()
+	l.mu.Lock()
+	l.stats.Waiting++
+	l.mu.Unlock()
+
+	err := l.acquireSlot(ctx)
+	if err == nil {
+		if err = l.waitToken(ctx); err != nil {
+			l.releaseSlot()
+		}
+	}
+
+	waited := time.Since(start)
+	l.mu.Lock()
+	l.stats.Waiting--
+	if err == nil {
+		l.stats.Requests++
+		l.stats.InFlight++
+		l.stats.TotalWait += waited
+		if waited > time.Millisecond {
+			l.stats.Waited++
+		}
+		if waited > l.stats.MaxWait {
+			l.stats.MaxWait = waited
+		}
+	}
+	l.mu.Unlock()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return func() {
+		l.mu.Lock()
+		l.stats.InFlight--
+		l.mu.Unlock()
+		l.releaseSlot()
+	}, nil
+}
+
+func (l *limiter) acquireSlot(ctx context.Context) error {
+	if l.slots == nil {
+		return ctx.Err()
+	}
+
+	select {
+	case l.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (l *limiter) releaseSlot() {
+	if l.slots != nil {
+		<-l.slots
+	}
+}
+
+// Reserves a token, then sleeps until the reservation is due
+func (l *limiter) waitToken(ctx context.Context) error {
+	if l.rate <= 0 {
+		return nil
+	}
+
+	l.mu.Lock()
+	now := time.Now()
+	if !l.last.IsZero() {
+		l.tokens += now.Sub(l.last).Seconds() * l.rate
+		if l.tokens > l.burst {
+			l.tokens = l.burst
+		}
+	}
+	l.last = now
+	l.tokens--
+	var delay time.Duration
+	if l.tokens < 0 {
+		delay = time.Duration(-l.tokens / l.rate * float64(time.Second))
+	}
+	l.mu.Unlock()
+
+	if err := sleepContext(ctx, delay); err != nil {
+		// hand back the reservation
+		l.mu.Lock()
+		l.tokens++
+		l.mu.Unlock()
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package alchemyapi
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestAnalyzerRateLimit(t *testing.T) {
+	apiKey := "foooooooooooooooooooooooooooooooooooobar"
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("{\"status\":\"OK\"}"))
+	}
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	analyzer, _ := NewAnalyzer(apiKey, WithBaseURL(server.URL), WithRateLimit(20, 1))
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := analyzer.Language("text", "foobar", url.Values{}); err != nil {
+				t.Errorf("should not raise exception, %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// the first token is available right away, 4 more at 20/s
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Errorf("5 calls at 20/s should take at least 200ms, but %v", elapsed)
+	}
+
+	stats := analyzer.LimiterStats()
+	if stats.Requests != 5 {
+		t.Errorf("want %d, but %d", 5, stats.Requests)
+	}
+	if stats.Waited == 0 || stats.TotalWait == 0 || stats.MaxWait == 0 {
+		t.Errorf("waits should be recorded, but %+v", stats)
+	}
+	if stats.Waiting != 0 || stats.InFlight != 0 {
+		t.Errorf("nothing should be pending, but %+v", stats)
+	}
+}
+
+func TestAnalyzerMaxInFlight(t *testing.T) {
+	apiKey := "foooooooooooooooooooooooooooooooooooobar"
+	var current, peak int32
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&current, 1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		atomic.AddInt32(&current, -1)
+		w.Write([]byte("{\"status\":\"OK\"}"))
+	}
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	analyzer, _ := NewAnalyzer(apiKey, WithBaseURL(server.URL), WithMaxInFlight(2))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			analyzer.Language("text", "foobar", url.Values{})
+		}()
+	}
+	wg.Wait()
+
+	if peak > 2 {
+		t.Errorf("want at most %d in flight, but %d", 2, peak)
+	}
+}
+
+func TestAnalyzerLimiterContext(t *testing.T) {
+	apiKey := "foooooooooooooooooooooooooooooooooooobar"
+	analyzer, _ := NewAnalyzer(apiKey, WithRateLimit(0.001, 1))
+	// drains the only token
+	analyzer.limiter.acquire(context.Background())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	_, err := analyzer.LanguageContext(ctx, "text", "foobar", url.Values{})
+	if err != context.DeadlineExceeded {
+		t.Errorf("want %v, but %v", context.DeadlineExceeded, err)
+	}
+}
@@ -0,0 +1,240 @@
+package alchemyapi
+
+/**
+  Copyright 2015 AlchemyAPI
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+      http://www.apache.org/licenses/LICENSE-2.0
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"sort"
+	"time"
+)
+
+// Logger receives one record per call, *slog.Logger satisfies it.
+type Logger interface {
+	LogAttrs(ctx context.Context, level slog.Level, msg string, attrs ...slog.Attr)
+}
+
+// CallInfo describes a single Analyzer call, retries included. It never
+// holds the api key.
+type CallInfo struct {
+	Endpoint     string        // the entry point arrange, e.g. "entities"
+	Flavor       string        // url, text, html or image
+	Path         string        // e.g. /text/TextGetRankedNamedEntities
+	OptionKeys   []string      // the keys of the options sent, sorted
+	PayloadSize  int           // bytes of the payload, text, url, html or image
+	ResponseSize int           // bytes of the (decompressed) response
+	Latency      time.Duration // the whole call, waits and retries included
+	StatusCode   int           // http status of the last attempt, 0 if none
+	Status       string        // status of the response, OK or ERROR
+	StatusInfo   string
+	Language     string
+	Transactions int64 // transactions charged, see Ledger
+	Attempts     int
+	CacheHit     bool
+	Err          error
+
+	requestBody  string
+	responseBody []byte
+}
+
+// Observer is notified once every call is over, e.g. to collect metrics.
+type Observer interface {
+	ObserveCall(ctx context.Context, info *CallInfo)
+}
+
+// CallStarter is an Observer also told when a call starts. The returned
+// context, passed on to the next CallStarter, is used for the call, the
+// outbound http request included, and handed back to the ObserveCall of
+// the same observer; e.g. to carry a tracing span.
+type CallStarter interface {
+	Observer
+	StartCall(ctx context.Context, info *CallInfo) context.Context
+}
+
+// MethodObserver is an Observer also told when an Analyzer method starts
+// and ends. A method makes one call, or one per chunk of a long text (see
+// WithChunking), all of them run in the context returned by StartMethod;
+// e.g. to make their spans children of the span of the method. Of the info
+// only Endpoint, Flavor, Path, PayloadSize, Latency and Err are set.
+type MethodObserver interface {
+	Observer
+	StartMethod(ctx context.Context, info *CallInfo) context.Context
+	EndMethod(ctx context.Context, info *CallInfo)
+}
+
+// Notifies the observer of every call, observers are called in the order
+// they are added.
+func WithObserver(observer Observer) Option {
+	return func(analyzer *Analyzer) {
+		if observer != nil {
+			analyzer.observers = append(analyzer.observers, observer)
+		}
+	}
+}
+
+// Logs every call with the given logger, successful calls at debug level
+// and failed ones at warn level.
+func WithLogger(logger Logger) Option {
+	return func(analyzer *Analyzer) {
+		analyzer.logger = logger
+	}
+}
+
+// Adds the request and response bodies, truncated to max bytes, to the
+// log records. The api key is never logged.
+func WithBodyLogging(max int) Option {
+	return func(analyzer *Analyzer) {
+		analyzer.logBodies = max
+	}
+}
+
+func newCallInfo(req *request) *CallInfo {
+	info := &CallInfo{
+		Endpoint:    req.arrange,
+		Flavor:      req.flavor,
+		Path:        entryPoints[req.arrange][req.flavor],
+		OptionKeys:  make([]string, 0, len(req.values)),
+		PayloadSize: len(req.values.Get(req.flavor)),
+	}
+	for k := range req.values {
+		info.OptionKeys = append(info.OptionKeys, k)
+	}
+	sort.Strings(info.OptionKeys)
+
+	if req.binData != nil {
+		info.PayloadSize = len(req.binData)
+		info.requestBody = fmt.Sprintf("<%d bytes image> %s", len(req.binData), req.values.Encode())
+	} else {
+		info.requestBody = req.values.Encode()
+	}
+	return info
+}
+
+// Notes the response and decodes its status
+func (info *CallInfo) setResponse(data []byte) (*statusEnvelope, error) {
+	info.ResponseSize = len(data)
+	info.responseBody = data
+
+	status := new(statusEnvelope)
+	if err := json.Unmarshal(data, status); err != nil {
+		return nil, err
+	}
+	info.Status = status.Status
+	info.StatusInfo = status.StatusInfo
+	info.Language = status.Language
+	info.Transactions = status.transactions()
+	return status, nil
+}
+
+// Marks the context of a method, the calls made in it are not methods
+type methodKey struct{}
+
+// Notifies the MethodObservers that a method starts, the returned func
+// notifies them it ended. Nothing is started for the calls of a method
+// already started, e.g. the ones of the chunks of a text.
+func (analyzer *Analyzer) startMethod(ctx context.Context, arrange, flavor string, payloadSize int) (context.Context, func(error)) {
+	var observers []MethodObserver
+	for _, observer := range analyzer.observers {
+		if methodObserver, ok := observer.(MethodObserver); ok {
+			observers = append(observers, methodObserver)
+		}
+	}
+	if len(observers) == 0 || ctx.Value(methodKey{}) != nil {
+		return ctx, func(error) {}
+	}
+
+	info := &CallInfo{
+		Endpoint:    arrange,
+		Flavor:      flavor,
+		Path:        entryPoints[arrange][flavor],
+		PayloadSize: payloadSize,
+	}
+	ctx = context.WithValue(ctx, methodKey{}, true)
+	started := make([]context.Context, len(observers))
+	for i, observer := range observers {
+		ctx = observer.StartMethod(ctx, info)
+		started[i] = ctx
+	}
+
+	start := time.Now()
+	return ctx, func(err error) {
+		info.Latency = time.Since(start)
+		info.Err = err
+		for i, observer := range observers {
+			observer.EndMethod(started[i], info)
+		}
+	}
+}
+
+func (analyzer *Analyzer) logCall(ctx context.Context, info *CallInfo) {
+	if analyzer.logger == nil {
+		return
+	}
+
+	attrs := []slog.Attr{
+		slog.String("endpoint", info.Endpoint),
+		slog.String("flavor", info.Flavor),
+		slog.String("path", info.Path),
+		slog.Any("options", info.OptionKeys),
+		slog.Int("payload_size", info.PayloadSize),
+		slog.Int("response_size", info.ResponseSize),
+		slog.Duration("latency", info.Latency),
+		slog.Int("http_status", info.StatusCode),
+		slog.String("status", info.Status),
+		slog.String("status_info", info.StatusInfo),
+		slog.Int64("transactions", info.Transactions),
+		slog.Int("attempts", info.Attempts),
+		slog.Bool("cache_hit", info.CacheHit),
+	}
+	if analyzer.logBodies > 0 {
+		attrs = append(attrs,
+			slog.String("request_body", truncate(info.requestBody, analyzer.logBodies)),
+			slog.String("response_body", truncate(string(info.responseBody), analyzer.logBodies)),
+		)
+	}
+
+	level := slog.LevelDebug
+	if info.Err != nil {
+		level = slog.LevelWarn
+		attrs = append(attrs, slog.String("error", info.Err.Error()))
+	}
+	analyzer.logger.LogAttrs(ctx, level, "alchemyapi call", attrs...)
+}
+
+// The image flavor sends the api key in the query, which a failed request
+// repeats in its *url.Error; the error is returned, logged and traced.
+func redactURLError(err error) error {
+	var urlErr *url.Error
+	if !errors.As(err, &urlErr) {
+		return err
+	}
+	if u, parseErr := url.Parse(urlErr.URL); parseErr == nil {
+		u.RawQuery = scrubAPIKey(u.RawQuery)
+		urlErr.URL = u.String()
+	} else {
+		urlErr.URL = "(unparsable url)"
+	}
+	return err
+}
+
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	return s[:max] + "...(truncated)"
+}
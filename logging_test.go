@@ -0,0 +1,287 @@
+package alchemyapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestAnalyzerLogger(t *testing.T) {
+	apiKey := "foooooooooooooooooooooooooooooooooooobar"
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		switch r.FormValue("text") {
+		case "bad":
+			w.Write([]byte("{\"status\":\"ERROR\",\"statusInfo\":\"unsupported-text-language\"}"))
+		default:
+			w.Write([]byte("{\"status\":\"OK\",\"language\":\"english\",\"totalTransactions\":\"2\",\"text\":\"" + strings.Repeat("x", 100) + "\"}"))
+		}
+	}
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	buf := new(bytes.Buffer)
+	logger := slog.New(slog.NewJSONHandler(buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	analyzer, _ := NewAnalyzer(apiKey, WithBaseURL(server.URL), WithLogger(logger), WithBodyLogging(32))
+
+	analyzer.Entities("text", "foobar", url.Values{"sentiment": {"1"}})
+	analyzer.Entities("text", "bad", url.Values{})
+
+	if strings.Contains(buf.String(), apiKey) {
+		t.Error("the api key should never be logged")
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("want %d records, but %d", 2, len(lines))
+	}
+
+	record := map[string]interface{}{}
+	json.Unmarshal([]byte(lines[0]), &record)
+	want := map[string]interface{}{
+		"level":        "DEBUG",
+		"endpoint":     "entities",
+		"flavor":       "text",
+		"path":         "/text/TextGetRankedNamedEntities",
+		"payload_size": float64(6),
+		"http_status":  float64(200),
+		"status":       "OK",
+		"transactions": float64(2),
+		"request_body": "sentiment=1&text=foobar",
+	}
+	for k, v := range want {
+		if record[k] != v {
+			t.Errorf("%s want %v, but %v", k, v, record[k])
+		}
+	}
+	if body, _ := record["response_body"].(string); !strings.HasSuffix(body, "...(truncated)") || len(body) != 32+len("...(truncated)") {
+		t.Errorf("the response body should be truncated, but %s", body)
+	}
+
+	record = map[string]interface{}{}
+	json.Unmarshal([]byte(lines[1]), &record)
+	if record["level"] != "WARN" || record["status_info"] != "unsupported-text-language" || record["error"] == nil {
+		t.Errorf("unexpected record %v", record)
+	}
+}
+
+func TestAnalyzerLoggerWithoutBodies(t *testing.T) {
+	apiKey := "foooooooooooooooooooooooooooooooooooobar"
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("{\"status\":\"OK\"}"))
+	}
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	buf := new(bytes.Buffer)
+	logger := slog.New(slog.NewJSONHandler(buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	analyzer, _ := NewAnalyzer(apiKey, WithBaseURL(server.URL), WithLogger(logger))
+	analyzer.Language("text", "secret payload", url.Values{})
+
+	if strings.Contains(buf.String(), "secret payload") || strings.Contains(buf.String(), "request_body") {
+		t.Errorf("bodies should not be logged by default, but %s", buf.String())
+	}
+}
+
+func TestAnalyzerTransportFailureRedactsAPIKey(t *testing.T) {
+	apiKey := "foooooooooooooooooooooooooooooooooooobar"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.Close()
+
+	path := filepath.Join(t.TempDir(), "face.jpg")
+	os.WriteFile(path, []byte("image"), 0600)
+
+	buf := new(bytes.Buffer)
+	logger := slog.New(slog.NewJSONHandler(buf, nil))
+	analyzer, _ := NewAnalyzer(apiKey, WithBaseURL(server.URL), WithLogger(logger))
+
+	_, err := analyzer.Face("image", path, url.Values{})
+	if err == nil {
+		t.Fatal("want an error from a closed server, but nil")
+	}
+	if strings.Contains(err.Error(), apiKey) {
+		t.Errorf("the error should not hold the api key, but %v", err)
+	}
+	if !strings.Contains(buf.String(), "error") || strings.Contains(buf.String(), apiKey) {
+		t.Errorf("the api key should never be logged, but %s", buf.String())
+	}
+	var urlErr *url.Error
+	if !errors.As(err, &urlErr) || !strings.Contains(urlErr.URL, "/image/ImageGetRankedImageFaceTags") {
+		t.Errorf("the url should be kept without the key, but %v", err)
+	}
+}
+
+type ctxKey struct{}
+
+type recordingObserver struct {
+	started  []string
+	observed []string
+	sawValue bool
+}
+
+func (o *recordingObserver) StartCall(ctx context.Context, info *CallInfo) context.Context {
+	o.started = append(o.started, info.Endpoint)
+	return context.WithValue(ctx, ctxKey{}, "span")
+}
+
+func (o *recordingObserver) ObserveCall(ctx context.Context, info *CallInfo) {
+	o.observed = append(o.observed, info.Endpoint+"/"+info.Status)
+	o.sawValue = ctx.Value(ctxKey{}) == "span"
+}
+
+func TestAnalyzerObserver(t *testing.T) {
+	apiKey := "foooooooooooooooooooooooooooooooooooobar"
+	var outbound interface{}
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("{\"status\":\"OK\"}"))
+	}
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	transport := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		outbound = req.Context().Value(ctxKey{})
+		return http.DefaultTransport.RoundTrip(req)
+	})
+
+	observer := &recordingObserver{}
+	analyzer, _ := NewAnalyzer(apiKey, WithBaseURL(server.URL), WithTransport(transport), WithObserver(observer))
+	analyzer.Keywords("url", "http://example.com", url.Values{})
+
+	if len(observer.started) != 1 || observer.started[0] != "keywords" {
+		t.Errorf("unexpected starts %v", observer.started)
+	}
+	if len(observer.observed) != 1 || observer.observed[0] != "keywords/OK" {
+		t.Errorf("unexpected observations %v", observer.observed)
+	}
+	if !observer.sawValue || outbound != "span" {
+		t.Error("the context of StartCall should reach the request and ObserveCall")
+	}
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+type methodCtxKey struct{}
+
+type methodObserver struct {
+	mu       sync.Mutex
+	started  []string
+	ended    []string
+	calls    int
+	inMethod int
+}
+
+func (o *methodObserver) StartMethod(ctx context.Context, info *CallInfo) context.Context {
+	o.started = append(o.started, info.Endpoint)
+	return context.WithValue(ctx, methodCtxKey{}, info.Endpoint)
+}
+
+func (o *methodObserver) EndMethod(ctx context.Context, info *CallInfo) {
+	o.ended = append(o.ended, fmt.Sprintf("%s/%v", info.Endpoint, info.Err != nil))
+}
+
+func (o *methodObserver) StartCall(ctx context.Context, info *CallInfo) context.Context {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.calls++
+	if ctx.Value(methodCtxKey{}) == info.Endpoint {
+		o.inMethod++
+	}
+	return ctx
+}
+
+func (o *methodObserver) ObserveCall(ctx context.Context, info *CallInfo) {}
+
+func TestAnalyzerMethodObserver(t *testing.T) {
+	apiKey := "foooooooooooooooooooooooooooooooooooobar"
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.PostForm.Get("text") == "bad" {
+			w.Write([]byte("{\"status\":\"ERROR\",\"statusInfo\":\"unsupported-text-language\"}"))
+			return
+		}
+		w.Write([]byte("{\"status\":\"OK\",\"docSentiment\":{\"type\":\"neutral\"}}"))
+	}
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	observer := &methodObserver{}
+	analyzer, _ := NewAnalyzer(apiKey, WithBaseURL(server.URL), WithObserver(observer), WithChunking(ChunkOptions{MaxBytes: 40}))
+
+	// two chunks, two calls in one method
+	text := "good news and more good news, really.\n\nbad news and even more bad news, sadly."
+	if _, err := analyzer.Sentiment("text", text, url.Values{}); err != nil {
+		t.Fatal(err)
+	}
+	analyzer.Keywords("text", "bad", url.Values{})
+
+	if strings.Join(observer.started, ",") != "sentiment,keywords" {
+		t.Errorf("want %v, but %v", "sentiment,keywords", observer.started)
+	}
+	if strings.Join(observer.ended, ",") != "sentiment/false,keywords/true" {
+		t.Errorf("want %v, but %v", "sentiment/false,keywords/true", observer.ended)
+	}
+	if observer.calls != 3 || observer.inMethod != 3 {
+		t.Errorf("every call should run in the context of its method, but %d of %d", observer.inMethod, observer.calls)
+	}
+}
+
+type spanKey struct{}
+
+// Carries its name in the context like a tracer carries its span
+type namedObserver struct {
+	name       string
+	mismatches int
+}
+
+func (o *namedObserver) StartMethod(ctx context.Context, info *CallInfo) context.Context {
+	return context.WithValue(ctx, spanKey{}, o.name)
+}
+
+func (o *namedObserver) EndMethod(ctx context.Context, info *CallInfo) {
+	if ctx.Value(spanKey{}) != o.name {
+		o.mismatches++
+	}
+}
+
+func (o *namedObserver) StartCall(ctx context.Context, info *CallInfo) context.Context {
+	return context.WithValue(ctx, spanKey{}, o.name)
+}
+
+func (o *namedObserver) ObserveCall(ctx context.Context, info *CallInfo) {
+	if ctx.Value(spanKey{}) != o.name {
+		o.mismatches++
+	}
+}
+
+func TestAnalyzerObserversOwnContext(t *testing.T) {
+	apiKey := "foooooooooooooooooooooooooooooooooooobar"
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("{\"status\":\"OK\"}"))
+	}
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	first, second := &namedObserver{name: "first"}, &namedObserver{name: "second"}
+	analyzer, _ := NewAnalyzer(apiKey, WithBaseURL(server.URL), WithObserver(first), WithObserver(second))
+	if _, err := analyzer.Language("text", "foobar", url.Values{}); err != nil {
+		t.Fatal(err)
+	}
+	if first.mismatches != 0 || second.mismatches != 0 {
+		t.Errorf("every observer should get back its own context, but %d and %d mismatches", first.mismatches, second.mismatches)
+	}
+}
@@ -0,0 +1,148 @@
+/*
+	   Package metrics instruments Analyzer traffic with Prometheus metrics.
+
+		collector := metrics.NewCollector("")
+		prometheus.MustRegister(collector)
+
+		analyzer, err := alchemyapi.NewAnalyzer(key, alchemyapi.WithObserver(collector))
+
+	   Every metric is labeled by endpoint (the entry point arrange, e.g.
+	   "entities") and flavor; errors additionally by class, see
+	   alchemyapi.ErrorClass.
+*/
+package metrics
+
+/**
+  Copyright 2015 AlchemyAPI
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+      http://www.apache.org/licenses/LICENSE-2.0
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+import (
+	"context"
+
+	alchemyapi "github.com/elvuel/alchemyapi_go"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const DefaultNamespace = "alchemyapi"
+
+// Collector is both an alchemyapi.Observer and a prometheus.Collector.
+type Collector struct {
+	requests     *prometheus.CounterVec
+	errors       *prometheus.CounterVec
+	transactions *prometheus.CounterVec
+	cacheHits    *prometheus.CounterVec
+	latency      *prometheus.HistogramVec
+	requestSize  *prometheus.HistogramVec
+	responseSize *prometheus.HistogramVec
+}
+
+var _ alchemyapi.Observer = (*Collector)(nil)
+var _ prometheus.Collector = (*Collector)(nil)
+
+// Creates new Collector, the metric names are prefixed with the namespace
+// (DefaultNamespace if empty). The series of every entry point start at 0.
+func NewCollector(namespace string) *Collector {
+	if namespace == "" {
+		namespace = DefaultNamespace
+	}
+	labels := []string{"endpoint", "flavor"}
+	sizes := prometheus.ExponentialBuckets(256, 4, 8) // 256B .. 4MB
+
+	collector := &Collector{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "requests_total",
+			Help:      "Analyzer calls, cache hits included.",
+		}, labels),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "errors_total",
+			Help:      "Failed Analyzer calls by error class.",
+		}, append(labels, "class")),
+		transactions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "transactions_total",
+			Help:      "Transactions charged by AlchemyAPI.",
+		}, labels),
+		cacheHits: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "cache_hits_total",
+			Help:      "Analyzer calls served from the cache.",
+		}, labels),
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "request_duration_seconds",
+			Help:      "Analyzer call latency, waits and retries included.",
+			Buckets:   prometheus.DefBuckets,
+		}, labels),
+		requestSize: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "request_size_bytes",
+			Help:      "Payload size of the Analyzer calls.",
+			Buckets:   sizes,
+		}, labels),
+		responseSize: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "response_size_bytes",
+			Help:      "Response size of the Analyzer calls.",
+			Buckets:   sizes,
+		}, labels),
+	}
+
+	for arrange, flavors := range alchemyapi.GetEntryPoints() {
+		for flavor := range flavors {
+			collector.requests.WithLabelValues(arrange, flavor)
+			collector.transactions.WithLabelValues(arrange, flavor)
+		}
+	}
+	return collector
+}
+
+func (collector *Collector) ObserveCall(ctx context.Context, info *alchemyapi.CallInfo) {
+	endpoint, flavor := info.Endpoint, info.Flavor
+
+	collector.requests.WithLabelValues(endpoint, flavor).Inc()
+	collector.latency.WithLabelValues(endpoint, flavor).Observe(info.Latency.Seconds())
+	collector.requestSize.WithLabelValues(endpoint, flavor).Observe(float64(info.PayloadSize))
+	if info.ResponseSize > 0 {
+		collector.responseSize.WithLabelValues(endpoint, flavor).Observe(float64(info.ResponseSize))
+	}
+
+	switch {
+	case info.Err != nil:
+		collector.errors.WithLabelValues(endpoint, flavor, alchemyapi.ErrorClass(info.Err)).Inc()
+	case info.CacheHit:
+		collector.cacheHits.WithLabelValues(endpoint, flavor).Inc()
+	default:
+		collector.transactions.WithLabelValues(endpoint, flavor).Add(float64(info.Transactions))
+	}
+}
+
+func (collector *Collector) Describe(ch chan<- *prometheus.Desc) {
+	collector.requests.Describe(ch)
+	collector.errors.Describe(ch)
+	collector.transactions.Describe(ch)
+	collector.cacheHits.Describe(ch)
+	collector.latency.Describe(ch)
+	collector.requestSize.Describe(ch)
+	collector.responseSize.Describe(ch)
+}
+
+func (collector *Collector) Collect(ch chan<- prometheus.Metric) {
+	collector.requests.Collect(ch)
+	collector.errors.Collect(ch)
+	collector.transactions.Collect(ch)
+	collector.cacheHits.Collect(ch)
+	collector.latency.Collect(ch)
+	collector.requestSize.Collect(ch)
+	collector.responseSize.Collect(ch)
+}
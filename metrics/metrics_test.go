@@ -0,0 +1,75 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	alchemyapi "github.com/elvuel/alchemyapi_go"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestCollector(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		switch r.FormValue("text") {
+		case "bad":
+			w.Write([]byte("{\"status\":\"ERROR\",\"statusInfo\":\"daily-transaction-limit-exceeded\"}"))
+		default:
+			w.Write([]byte("{\"status\":\"OK\",\"totalTransactions\":\"2\"}"))
+		}
+	}
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	collector := NewCollector("")
+	registry := prometheus.NewPedanticRegistry()
+	registry.MustRegister(collector)
+
+	analyzer, _ := alchemyapi.NewAnalyzer(strings.Repeat("0", 40),
+		alchemyapi.WithBaseURL(server.URL),
+		alchemyapi.WithObserver(collector),
+	)
+	analyzer.Entities("text", "foobar", url.Values{})
+	analyzer.Entities("text", "foobar", url.Values{})
+	analyzer.Entities("text", "bad", url.Values{})
+
+	if got := testutil.ToFloat64(collector.requests.WithLabelValues("entities", "text")); got != 3 {
+		t.Errorf("want %v, but %v", 3, got)
+	}
+	if got := testutil.ToFloat64(collector.transactions.WithLabelValues("entities", "text")); got != 4 {
+		t.Errorf("want %v, but %v", 4, got)
+	}
+	if got := testutil.ToFloat64(collector.errors.WithLabelValues("entities", "text", "daily_limit_exceeded")); got != 1 {
+		t.Errorf("want %v, but %v", 1, got)
+	}
+
+	// scrape
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := map[string]bool{}
+	for _, family := range families {
+		names[family.GetName()] = true
+	}
+	for _, name := range []string{
+		"alchemyapi_requests_total",
+		"alchemyapi_errors_total",
+		"alchemyapi_transactions_total",
+		"alchemyapi_request_duration_seconds",
+		"alchemyapi_request_size_bytes",
+		"alchemyapi_response_size_bytes",
+	} {
+		if !names[name] {
+			t.Errorf("%s should be scraped", name)
+		}
+	}
+
+	if n := testutil.CollectAndCount(collector, "alchemyapi_request_duration_seconds"); n != 1 {
+		t.Errorf("want %d latency series, but %d", 1, n)
+	}
+}
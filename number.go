@@ -0,0 +1,179 @@
+package alchemyapi
+
+/**
+  Copyright 2015 AlchemyAPI
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+      http://www.apache.org/licenses/LICENSE-2.0
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// The API sends most numbers as strings, sometimes empty ones. Float, Int
+// and Bool decode from both JSON strings and plain values; empty strings
+// and null decode to zero (false). Fields formerly declared float64 or
+// int64 use them too, convert them where a plain type is needed, e.g.
+// float64(response.DocSentiment.Score).
+
+// Float is a relevance or score.
+type Float float64
+
+func (f *Float) UnmarshalJSON(data []byte) error {
+	s, err := unquoteNumber(data)
+	if err != nil || s == "" {
+		return err
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return fmt.Errorf("alchemyapi: invalid number %s", data)
+	}
+	*f = Float(v)
+	return nil
+}
+
+// Int is a count.
+type Int int64
+
+func (i *Int) UnmarshalJSON(data []byte) error {
+	s, err := unquoteNumber(data)
+	if err != nil || s == "" {
+		return err
+	}
+	v, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		f, ferr := strconv.ParseFloat(s, 64)
+		if ferr != nil {
+			return fmt.Errorf("alchemyapi: invalid number %s", data)
+		}
+		v = int64(f)
+	}
+	*i = Int(v)
+	return nil
+}
+
+// Bool is a flag like Taxonomy.Confident, sent as "yes" or "no".
+type Bool bool
+
+func (b *Bool) UnmarshalJSON(data []byte) error {
+	s, err := unquoteNumber(data)
+	if err != nil {
+		return err
+	}
+	switch strings.ToLower(s) {
+	case "yes", "true", "1":
+		*b = true
+	case "no", "false", "0", "":
+		*b = false
+	default:
+		return fmt.Errorf("alchemyapi: invalid flag %s", data)
+	}
+	return nil
+}
+
+// The trimmed content of a JSON string, or the literal itself, null is empty
+func unquoteNumber(data []byte) (string, error) {
+	data = bytes.TrimSpace(data)
+	if string(data) == "null" {
+		return "", nil
+	}
+	if len(data) > 0 && data[0] == '"' {
+		s, err := strconv.Unquote(string(data))
+		if err != nil {
+			return "", fmt.Errorf("alchemyapi: invalid string %s", data)
+		}
+		return strings.TrimSpace(s), nil
+	}
+	return string(data), nil
+}
+
+// Items with value at least min, in their order
+func above[T any](items []T, min Float, value func(*T) Float) []T {
+	var filtered []T
+	for i := range items {
+		if value(&items[i]) >= min {
+			filtered = append(filtered, items[i])
+		}
+	}
+	return filtered
+}
+
+// The n items with the highest value, sorted by descending value. The
+// items themselves are left untouched.
+func topN[T any](items []T, n int, value func(*T) Float) []T {
+	sorted := append([]T(nil), items...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return value(&sorted[i]) > value(&sorted[j])
+	})
+	if n >= 0 && n < len(sorted) {
+		sorted = sorted[:n]
+	}
+	return sorted
+}
+
+func conceptRelevance(concept *Concept) Float       { return concept.Relevance }
+func entityRelevance(entity *Entity) Float          { return entity.Relevance }
+func keywordRelevance(keyword *Keyword) Float       { return keyword.Relevance }
+func imageKeywordScore(keyword *ImageKeyword) Float { return keyword.Score }
+func taxonomyScore(taxonomy *Taxonomy) Float        { return taxonomy.Score }
+
+// Entities with a relevance of at least min
+func (response *EntitiesResponse) Above(min Float) []Entity {
+	return above(response.Entities, min, entityRelevance)
+}
+
+// The n most relevant entities
+func (response *EntitiesResponse) TopN(n int) []Entity {
+	return topN(response.Entities, n, entityRelevance)
+}
+
+// Keywords with a relevance of at least min
+func (response *KeywordsResponse) Above(min Float) []Keyword {
+	return above(response.Keywords, min, keywordRelevance)
+}
+
+// The n most relevant keywords
+func (response *KeywordsResponse) TopN(n int) []Keyword {
+	return topN(response.Keywords, n, keywordRelevance)
+}
+
+// Concepts with a relevance of at least min
+func (response *ConceptsResponse) Above(min Float) []Concept {
+	return above(response.Concepts, min, conceptRelevance)
+}
+
+// The n most relevant concepts
+func (response *ConceptsResponse) TopN(n int) []Concept {
+	return topN(response.Concepts, n, conceptRelevance)
+}
+
+// Image keywords with a score of at least min
+func (response *ImageTagResponse) Above(min Float) []ImageKeyword {
+	return above(response.ImageKeywords, min, imageKeywordScore)
+}
+
+// The n image keywords with the highest score
+func (response *ImageTagResponse) TopN(n int) []ImageKeyword {
+	return topN(response.ImageKeywords, n, imageKeywordScore)
+}
+
+// Taxonomies with a score of at least min
+func (response *TaxonomyResponse) Above(min Float) []Taxonomy {
+	return above(response.Taxonomies, min, taxonomyScore)
+}
+
+// The n taxonomies with the highest score
+func (response *TaxonomyResponse) TopN(n int) []Taxonomy {
+	return topN(response.Taxonomies, n, taxonomyScore)
+}
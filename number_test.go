@@ -0,0 +1,109 @@
+package alchemyapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestNumberDecoding(t *testing.T) {
+	var entities EntitiesResponse
+	data := `{"entities":[
+		{"text":"a","relevance":"0.9","count":"3"},
+		{"text":"b","relevance":0.4,"count":2},
+		{"text":"c","relevance":"","count":null},
+		{"text":"d","relevance":"0.7","count":"1.0"}
+	]}`
+	if err := json.Unmarshal([]byte(data), &entities); err != nil {
+		t.Fatal(err)
+	}
+	got := entities.Entities
+	if got[0].Relevance != 0.9 || got[0].Count != 3 || got[1].Relevance != 0.4 || got[1].Count != 2 {
+		t.Errorf("unexpected entities %v", got)
+	}
+	if got[2].Relevance != 0 || got[2].Count != 0 || got[3].Count != 1 {
+		t.Errorf("unexpected entities %v", got)
+	}
+
+	var taxonomy TaxonomyResponse
+	data = `{"taxonomy":[{"label":"/a","score":"0.5","confident":"no"},{"label":"/b","score":0.8},{"label":"/c","score":"0.1","confident":"yes"}]}`
+	if err := json.Unmarshal([]byte(data), &taxonomy); err != nil {
+		t.Fatal(err)
+	}
+	if taxonomy.Taxonomies[0].Confident || taxonomy.Taxonomies[1].Confident || !taxonomy.Taxonomies[2].Confident {
+		t.Errorf("unexpected taxonomies %v", taxonomy.Taxonomies)
+	}
+
+	var face FaceResponse
+	data = `{"totalTransactions":4,"imageFaces":[{"positionX":"10","positionY":20,"width":"","height":"30"}]}`
+	if err := json.Unmarshal([]byte(data), &face); err != nil {
+		t.Fatal(err)
+	}
+	if f := face.ImageFaces[0]; face.TotalTransactions != 4 || f.PositionX != 10 || f.PositionY != 20 || f.Width != 0 || f.Height != 30 {
+		t.Errorf("unexpected faces %v", face)
+	}
+
+	var authors AuthorsResponse
+	if err := json.Unmarshal([]byte(`{"totalTransactions":"","authors":{"confident":"yes"}}`), &authors); err != nil {
+		t.Fatal(err)
+	}
+	if !authors.Authors.Confident {
+		t.Errorf("want %v, but %v", true, authors.Authors.Confident)
+	}
+
+	var sentiment Sentiment
+	if err := json.Unmarshal([]byte(`{"score":"abc"}`), &sentiment); err == nil {
+		t.Error("should raise exception for invalid numbers")
+	}
+}
+
+func TestAnalyzerNumberDecoding(t *testing.T) {
+	apiKey := "foooooooooooooooooooooooooooooooooooobar"
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case entryPoints["face"]["url"]:
+			w.Write([]byte(`{"status":"OK","totalTransactions":"","imageFaces":[{"positionX":12,"positionY":" 7","width":"","height":"30"}]}`))
+		default:
+			w.Write([]byte(`{"status":"OK","totalTransactions":3,"docSentiment":{"type":"positive","score":0.5,"mixed":""}}`))
+		}
+	}
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	analyzer, _ := NewAnalyzer(apiKey, WithBaseURL(server.URL))
+	face, err := analyzer.Face("url", "http://example.com/a.jpg", url.Values{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f := face.ImageFaces[0]; face.TotalTransactions != 0 || f.PositionX != 12 || f.PositionY != 7 || f.Width != 0 || f.Height != 30 {
+		t.Errorf("unexpected faces %v", face)
+	}
+
+	sentiment, err := analyzer.Sentiment("text", "foobar", url.Values{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sentiment.TotalTransactions != 3 || sentiment.DocSentiment.Score != 0.5 || sentiment.DocSentiment.Mixed != 0 {
+		t.Errorf("unexpected sentiment %v", sentiment)
+	}
+}
+
+func TestAboveTopN(t *testing.T) {
+	response := &KeywordsResponse{Keywords: []Keyword{
+		{Text: "a", Relevance: 0.2}, {Text: "b", Relevance: 0.9}, {Text: "c", Relevance: 0.5},
+	}}
+
+	above := response.Above(0.5)
+	if len(above) != 2 || above[0].Text != "b" || above[1].Text != "c" {
+		t.Errorf("unexpected keywords %v", above)
+	}
+	top := response.TopN(2)
+	if len(top) != 2 || top[0].Text != "b" || top[1].Text != "c" {
+		t.Errorf("unexpected keywords %v", top)
+	}
+	if len(response.TopN(10)) != 3 || response.Keywords[0].Text != "a" {
+		t.Error("the response should be left untouched")
+	}
+}
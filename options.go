@@ -0,0 +1,61 @@
+package alchemyapi
+
+/**
+  Copyright 2015 AlchemyAPI
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+      http://www.apache.org/licenses/LICENSE-2.0
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+import (
+	"net/http"
+	"strings"
+)
+
+const (
+	DefaultBaseURL   = "http://access.alchemyapi.com/calls"
+	DefaultUserAgent = "alchemyapi_go/" + Version
+)
+
+// Option configures an Analyzer, see NewAnalyzer.
+type Option func(*Analyzer)
+
+// Uses the given client for every request. The client may be shared
+// between analyzers, so keep-alive connections are pooled by its transport.
+func WithHTTPClient(client *http.Client) Option {
+	return func(analyzer *Analyzer) {
+		if client != nil {
+			analyzer.client = client
+		}
+	}
+}
+
+// Uses a client built around the given transport, e.g. one configured with
+// a proxy, custom TLS roots or connection limits.
+func WithTransport(transport http.RoundTripper) Option {
+	return func(analyzer *Analyzer) {
+		if transport != nil {
+			analyzer.client = &http.Client{Transport: transport}
+		}
+	}
+}
+
+// Overrides the default base url (DefaultBaseURL).
+func WithBaseURL(baseUrl string) Option {
+	return func(analyzer *Analyzer) {
+		analyzer.baseUrl = strings.TrimSuffix(baseUrl, "/")
+	}
+}
+
+// Overrides the User-Agent header sent along with every request.
+func WithUserAgent(userAgent string) Option {
+	return func(analyzer *Analyzer) {
+		analyzer.userAgent = userAgent
+	}
+}
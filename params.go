@@ -0,0 +1,386 @@
+package alchemyapi
+
+/**
+  Copyright 2015 AlchemyAPI
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+      http://www.apache.org/licenses/LICENSE-2.0
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidOption = errors.New("invalid option")
+
+// OptionError is returned, before anything is sent, for an option unknown
+// to the endpoint, an invalid value or a conflicting combination.
+type OptionError struct {
+	Endpoint string
+	Option   string
+	Reason   string
+}
+
+func (e *OptionError) Error() string {
+	return fmt.Sprintf("%s option %s: %s", e.Endpoint, e.Option, e.Reason)
+}
+
+func (e *OptionError) Unwrap() error {
+	return ErrInvalidOption
+}
+
+// Toggle is a 0/1 option, the zero value leaves the AlchemyAPI default.
+type Toggle int
+
+const (
+	Unset Toggle = iota
+	Enabled
+	Disabled
+)
+
+func (toggle Toggle) set(values url.Values, key string) {
+	switch toggle {
+	case Enabled:
+		values.Set(key, "1")
+	case Disabled:
+		values.Set(key, "0")
+	}
+}
+
+// Source selects the text a url or html call works on.
+type Source struct {
+	SourceText string // cleaned_or_raw (default), cleaned, raw, cquery, xpath or xpath_or_raw
+	CQuery     string // visual constraints query, requires SourceText cquery
+	XPath      string // requires SourceText xpath or xpath_or_raw
+}
+
+func (source Source) set(values url.Values) {
+	setString(values, "sourceText", source.SourceText)
+	setString(values, "cquery", source.CQuery)
+	setString(values, "xpath", source.XPath)
+}
+
+// Options of Sentiment and SentimentTargeted.
+type SentimentOptions struct {
+	Source
+	ShowSourceText Toggle
+}
+
+func (opts SentimentOptions) Values() (url.Values, error) {
+	values := url.Values{}
+	opts.Source.set(values)
+	opts.ShowSourceText.set(values, "showSourceText")
+	return values, ValidateOptions("sentiment", values)
+}
+
+// Options of Taxonomy.
+type TaxonomyOptions struct {
+	Source
+	ShowSourceText Toggle
+}
+
+func (opts TaxonomyOptions) Values() (url.Values, error) {
+	values := url.Values{}
+	opts.Source.set(values)
+	opts.ShowSourceText.set(values, "showSourceText")
+	return values, ValidateOptions("taxonomy", values)
+}
+
+// Options of Concepts.
+type ConceptsOptions struct {
+	Source
+	MaxRetrieve    int // 0 leaves the default (8)
+	LinkedData     Toggle
+	ShowSourceText Toggle
+}
+
+func (opts ConceptsOptions) Values() (url.Values, error) {
+	values := url.Values{}
+	opts.Source.set(values)
+	setInt(values, "maxRetrieve", opts.MaxRetrieve)
+	opts.LinkedData.set(values, "linkedData")
+	opts.ShowSourceText.set(values, "showSourceText")
+	return values, ValidateOptions("concepts", values)
+}
+
+// Options of Entities.
+type EntitiesOptions struct {
+	Source
+	MaxRetrieve    int // 0 leaves the default (50)
+	Disambiguate   Toggle
+	LinkedData     Toggle // requires Disambiguate
+	Coreference    Toggle
+	Quotations     Toggle
+	Sentiment      Toggle // one additional transaction
+	ShowSourceText Toggle
+}
+
+func (opts EntitiesOptions) Values() (url.Values, error) {
+	values := url.Values{}
+	opts.Source.set(values)
+	setInt(values, "maxRetrieve", opts.MaxRetrieve)
+	opts.Disambiguate.set(values, "disambiguate")
+	opts.LinkedData.set(values, "linkedData")
+	opts.Coreference.set(values, "coreference")
+	opts.Quotations.set(values, "quotations")
+	opts.Sentiment.set(values, "sentiment")
+	opts.ShowSourceText.set(values, "showSourceText")
+	return values, ValidateOptions("entities", values)
+}
+
+// Options of Keywords.
+type KeywordsOptions struct {
+	Source
+	MaxRetrieve        int    // 0 leaves the default (50)
+	KeywordExtractMode string // normal (default) or strict
+	Sentiment          Toggle // one additional transaction
+	ShowSourceText     Toggle
+}
+
+func (opts KeywordsOptions) Values() (url.Values, error) {
+	values := url.Values{}
+	opts.Source.set(values)
+	setInt(values, "maxRetrieve", opts.MaxRetrieve)
+	setString(values, "keywordExtractMode", opts.KeywordExtractMode)
+	opts.Sentiment.set(values, "sentiment")
+	opts.ShowSourceText.set(values, "showSourceText")
+	return values, ValidateOptions("keywords", values)
+}
+
+// Options of Relations.
+type RelationsOptions struct {
+	Source
+	MaxRetrieve              int // 0 leaves the default (50), at most 100
+	Sentiment                Toggle
+	Keywords                 Toggle
+	Entities                 Toggle
+	RequireEntities          Toggle
+	SentimentExcludeEntities Toggle
+	Disambiguate             Toggle
+	LinkedData               Toggle // requires Disambiguate
+	Coreference              Toggle
+	ShowSourceText           Toggle
+}
+
+func (opts RelationsOptions) Values() (url.Values, error) {
+	values := url.Values{}
+	opts.Source.set(values)
+	setInt(values, "maxRetrieve", opts.MaxRetrieve)
+	opts.Sentiment.set(values, "sentiment")
+	opts.Keywords.set(values, "keywords")
+	opts.Entities.set(values, "entities")
+	opts.RequireEntities.set(values, "requireEntities")
+	opts.SentimentExcludeEntities.set(values, "sentimentExcludeEntities")
+	opts.Disambiguate.set(values, "disambiguate")
+	opts.LinkedData.set(values, "linkedData")
+	opts.Coreference.set(values, "coreference")
+	opts.ShowSourceText.set(values, "showSourceText")
+	return values, ValidateOptions("relations", values)
+}
+
+// Options of Text, TextRaw and Title.
+type TextOptions struct {
+	UseMetadata  Toggle
+	ExtractLinks Toggle
+}
+
+func (opts TextOptions) Values() (url.Values, error) {
+	values := url.Values{}
+	opts.UseMetadata.set(values, "useMetadata")
+	opts.ExtractLinks.set(values, "extractLinks")
+	return values, ValidateOptions("text", values)
+}
+
+// Options of Face, ImageExtract and ImageTag.
+type ImageOptions struct {
+	ExtractMode string // trust-metadata or always-infer
+}
+
+func (opts ImageOptions) Values() (url.Values, error) {
+	values := url.Values{}
+	setString(values, "extractMode", opts.ExtractMode)
+	return values, ValidateOptions("image_extract", values)
+}
+
+// Options of Combined.
+type CombinedOptions struct {
+	Source
+	Extract        []string // page-image, entity, keyword, title, author, taxonomy, concept, relation, doc-sentiment
+	ExtractMode    string   // trust-metadata or always-infer, requires page-image
+	MaxRetrieve    int      // 0 leaves the default (50)
+	Disambiguate   Toggle
+	LinkedData     Toggle // requires Disambiguate
+	Coreference    Toggle
+	Quotations     Toggle
+	Sentiment      Toggle // one additional transaction
+	ShowSourceText Toggle
+}
+
+func (opts CombinedOptions) Values() (url.Values, error) {
+	values := url.Values{}
+	opts.Source.set(values)
+	setString(values, "extract", strings.Join(opts.Extract, ","))
+	setString(values, "extractMode", opts.ExtractMode)
+	setInt(values, "maxRetrieve", opts.MaxRetrieve)
+	opts.Disambiguate.set(values, "disambiguate")
+	opts.LinkedData.set(values, "linkedData")
+	opts.Coreference.set(values, "coreference")
+	opts.Quotations.set(values, "quotations")
+	opts.Sentiment.set(values, "sentiment")
+	opts.ShowSourceText.set(values, "showSourceText")
+	return values, ValidateOptions("combined", values)
+}
+
+func setString(values url.Values, key, value string) {
+	if value != "" {
+		values.Set(key, value)
+	}
+}
+
+func setInt(values url.Values, key string, value int) {
+	if value != 0 {
+		values.Set(key, strconv.Itoa(value))
+	}
+}
+
+var (
+	sourceOptions = []string{"showSourceText", "sourceText", "cquery", "xpath"}
+	entityOptions = []string{"disambiguate", "linkedData", "coreference", "quotations", "sentiment", "maxRetrieve"}
+)
+
+// the options documented for every arrange
+var knownOptions = map[string][]string{
+	"sentiment":          sourceOptions,
+	"sentiment_targeted": append([]string{"target"}, sourceOptions...),
+	"taxonomy":           sourceOptions,
+	"concepts":           append([]string{"maxRetrieve", "linkedData"}, sourceOptions...),
+	"entities":           append(entityOptions, sourceOptions...),
+	"keywords":           append([]string{"keywordExtractMode", "sentiment", "maxRetrieve"}, sourceOptions...),
+	"relations": append([]string{"sentiment", "keywords", "entities", "requireEntities", "sentimentExcludeEntities",
+		"disambiguate", "linkedData", "coreference", "maxRetrieve"}, sourceOptions...),
+	"text":             {"useMetadata", "extractLinks"},
+	"text_raw":         {"useMetadata", "extractLinks"},
+	"title":            {"useMetadata", "extractLinks"},
+	"face":             {"extractMode", "imagePostMode"},
+	"image_extract":    {"extractMode"},
+	"image_tag":        {"extractMode", "imagePostMode"},
+	"authors":          {},
+	"language":         {},
+	"feeds":            {},
+	"microformats":     {},
+	"combined":         append([]string{"extract", "extractMode"}, append(entityOptions, sourceOptions...)...),
+	"publication_date": {},
+}
+
+var (
+	toggleOptions = map[string]bool{
+		"showSourceText": true, "linkedData": true, "disambiguate": true, "coreference": true,
+		"quotations": true, "sentiment": true, "keywords": true, "entities": true, "requireEntities": true,
+		"sentimentExcludeEntities": true, "useMetadata": true, "extractLinks": true,
+	}
+	enumOptions = map[string][]string{
+		"sourceText":         {"cleaned_or_raw", "cleaned", "raw", "cquery", "xpath", "xpath_or_raw"},
+		"keywordExtractMode": {"normal", "strict"},
+		"extractMode":        {"trust-metadata", "always-infer"},
+		"imagePostMode":      {"raw", "not-raw"},
+	}
+	extractValues = []string{"page-image", "entity", "keyword", "title", "author", "taxonomy", "concept", "relation", "doc-sentiment"}
+)
+
+/*
+   Checks the options of an endpoint (an arrange of the entry points)
+   without sending anything: unknown keys, malformed values and conflicting
+   combinations are reported as an *OptionError. The flavor keys (url, text,
+   html), apikey and outputMode are always accepted.
+*/
+func ValidateOptions(arrange string, options url.Values) error {
+	known, ok := knownOptions[arrange]
+	if !ok {
+		return &OptionError{Endpoint: arrange, Option: "", Reason: "unknown endpoint"}
+	}
+
+	for key, values := range options {
+		switch key {
+		case "url", "text", "html", "apikey", "outputMode":
+			continue
+		}
+		if !contains(known, key) {
+			return &OptionError{Endpoint: arrange, Option: key, Reason: "unknown option"}
+		}
+		for _, value := range values {
+			if reason := checkOptionValue(key, value); reason != "" {
+				return &OptionError{Endpoint: arrange, Option: key, Reason: reason}
+			}
+		}
+	}
+
+	if options.Get("linkedData") == "1" && options.Get("disambiguate") == "0" {
+		return &OptionError{Endpoint: arrange, Option: "linkedData", Reason: "requires disambiguate"}
+	}
+	if options.Get("cquery") != "" && options.Get("sourceText") != "cquery" {
+		return &OptionError{Endpoint: arrange, Option: "cquery", Reason: "requires sourceText cquery"}
+	}
+	if xpath := options.Get("xpath"); xpath != "" && !strings.HasPrefix(options.Get("sourceText"), "xpath") {
+		return &OptionError{Endpoint: arrange, Option: "xpath", Reason: "requires sourceText xpath or xpath_or_raw"}
+	}
+	if arrange == "relations" {
+		if n, _ := strconv.Atoi(options.Get("maxRetrieve")); n > 100 {
+			return &OptionError{Endpoint: arrange, Option: "maxRetrieve", Reason: "at most 100"}
+		}
+	}
+	if arrange == "combined" && options.Get("extractMode") != "" &&
+		!contains(strings.Split(options.Get("extract"), ","), "page-image") {
+		return &OptionError{Endpoint: arrange, Option: "extractMode", Reason: "requires extract page-image"}
+	}
+	return nil
+}
+
+// The reason the value is invalid, empty when valid
+func checkOptionValue(key, value string) string {
+	if toggleOptions[key] && value != "0" && value != "1" {
+		return fmt.Sprintf("want 0 or 1, but %q", value)
+	}
+	if allowed, ok := enumOptions[key]; ok && !contains(allowed, value) {
+		return fmt.Sprintf("want one of %s, but %q", strings.Join(allowed, ", "), value)
+	}
+	switch key {
+	case "maxRetrieve":
+		if n, err := strconv.Atoi(value); err != nil || n < 1 {
+			return fmt.Sprintf("want a positive number, but %q", value)
+		}
+	case "extract":
+		for _, v := range strings.Split(value, ",") {
+			if !contains(extractValues, v) {
+				return fmt.Sprintf("unknown extraction %q", v)
+			}
+		}
+	}
+	return ""
+}
+
+func contains(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Validates the options of every call with ValidateOptions before
+// anything is sent, so a typo does not cost a transaction.
+func WithStrictOptions() Option {
+	return func(analyzer *Analyzer) {
+		analyzer.strictOptions = true
+	}
+}
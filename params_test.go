@@ -0,0 +1,89 @@
+package alchemyapi
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+)
+
+func TestEntitiesOptionsValues(t *testing.T) {
+	values, err := EntitiesOptions{MaxRetrieve: 20, Sentiment: Enabled, LinkedData: Disabled}.Values()
+	if err != nil {
+		t.Fatalf("should not be error, %v", err)
+	}
+
+	want := url.Values{"maxRetrieve": {"20"}, "sentiment": {"1"}, "linkedData": {"0"}}
+	if values.Encode() != want.Encode() {
+		t.Errorf("want %s, but %s", want.Encode(), values.Encode())
+	}
+}
+
+func TestOptionsConflicts(t *testing.T) {
+	cases := map[string]interface {
+		Values() (url.Values, error)
+	}{
+		"linkedData":         EntitiesOptions{LinkedData: Enabled, Disambiguate: Disabled},
+		"maxRetrieve":        RelationsOptions{MaxRetrieve: 101},
+		"keywordExtractMode": KeywordsOptions{KeywordExtractMode: "loose"},
+		"extractMode":        CombinedOptions{Extract: []string{"entity"}, ExtractMode: "always-infer"},
+		"extract":            CombinedOptions{Extract: []string{"entites"}},
+		"cquery":             SentimentOptions{Source: Source{CQuery: "article"}},
+	}
+
+	for option, opts := range cases {
+		_, err := opts.Values()
+		var optionErr *OptionError
+		if !errors.As(err, &optionErr) || optionErr.Option != option {
+			t.Errorf("want an error on %s, but %v", option, err)
+		}
+		if !errors.Is(err, ErrInvalidOption) {
+			t.Errorf("want %v, but %v", ErrInvalidOption, err)
+		}
+	}
+
+	if _, err := (CombinedOptions{Extract: []string{"page-image", "entity"}, ExtractMode: "always-infer"}).Values(); err != nil {
+		t.Errorf("should not be error, %v", err)
+	}
+}
+
+func TestValidateOptions(t *testing.T) {
+	if err := ValidateOptions("keywords", url.Values{"maxRetreive": {"5"}}); err == nil {
+		t.Error("a typo should be an error")
+	}
+	if err := ValidateOptions("entities", url.Values{"sentiment": {"yes"}}); err == nil {
+		t.Error("a toggle should be 0 or 1")
+	}
+	if err := ValidateOptions("foo", url.Values{}); err == nil {
+		t.Error("an unknown endpoint should be an error")
+	}
+	if err := ValidateOptions("sentiment_targeted", url.Values{"text": {"foo"}, "target": {"bar"}, "showSourceText": {"1"}}); err != nil {
+		t.Errorf("should not be error, %v", err)
+	}
+}
+
+func TestAnalyzerStrictOptions(t *testing.T) {
+	apiKey := "foooooooooooooooooooooooooooooooooooobar"
+	var calls int32
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.Write([]byte("{\"status\":\"OK\"}"))
+	}
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	analyzer, _ := NewAnalyzer(apiKey, WithBaseURL(server.URL), WithStrictOptions())
+	_, err := analyzer.Entities("text", "foobar", url.Values{"disambiguat": {"1"}})
+	if !errors.Is(err, ErrInvalidOption) {
+		t.Errorf("want %v, but %v", ErrInvalidOption, err)
+	}
+	if calls != 0 {
+		t.Errorf("invalid options should not be sent, but %d calls", calls)
+	}
+
+	if _, err := analyzer.Feeds("html", "<html></html>", "http://example.com", url.Values{}); err != nil {
+		t.Errorf("should not raise exception, %v", err)
+	}
+}
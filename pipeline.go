@@ -0,0 +1,159 @@
+package alchemyapi
+
+/**
+  Copyright 2015 AlchemyAPI
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+      http://www.apache.org/licenses/LICENSE-2.0
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/url"
+)
+
+// Response is implemented by every response type, giving access to the
+// status every AlchemyAPI answer carries.
+type Response interface {
+	ResponseStatus() (status, statusInfo string)
+}
+
+// A pointer to a response type
+type responsePtr[T any] interface {
+	*T
+	Response
+}
+
+/*
+   endpoint declares a call: the arrange of the entry points and the hooks
+   which tailor the common pipeline to it. A call runs
+
+   - the flavor check
+   - the before-send hooks, in order, on the request holding the options,
+     the extra values and the payload
+   - analyze, sending the request
+   - the decoding into T and the status check
+   - the after-receive hooks, in order, on the decoded response
+
+   A hook failing fails the call.
+*/
+type endpoint[T any] struct {
+	arrange      string
+	beforeSend   []func(analyzer *Analyzer, req *request) error
+	afterReceive []func(analyzer *Analyzer, req *request, response *T) error
+}
+
+var (
+	sentimentEndpoint         = &endpoint[SentimentResponse]{arrange: "sentiment"}
+	sentimentTargetedEndpoint = &endpoint[SentimentResponse]{
+		arrange:    "sentiment_targeted",
+		beforeSend: []func(*Analyzer, *request) error{requireTarget},
+	}
+	taxonomyEndpoint     = &endpoint[TaxonomyResponse]{arrange: "taxonomy"}
+	conceptsEndpoint     = &endpoint[ConceptsResponse]{arrange: "concepts"}
+	entitiesEndpoint     = &endpoint[EntitiesResponse]{arrange: "entities"}
+	keywordsEndpoint     = &endpoint[KeywordsResponse]{arrange: "keywords"}
+	relationsEndpoint    = &endpoint[RelationsResponse]{arrange: "relations"}
+	textEndpoint         = &endpoint[TextTitleResponse]{arrange: "text"}
+	textRawEndpoint      = &endpoint[TextTitleResponse]{arrange: "text_raw"}
+	titleEndpoint        = &endpoint[TextTitleResponse]{arrange: "title"}
+	imageExtractEndpoint = &endpoint[ImageExtractResponse]{arrange: "image_extract"}
+	faceEndpoint         = &endpoint[FaceResponse]{
+		arrange:      "face",
+		beforeSend:   []func(*Analyzer, *request) error{preprocessImage},
+		afterReceive: []func(*Analyzer, *request, *FaceResponse) error{rescaleFaces},
+	}
+	imageTagEndpoint = &endpoint[ImageTagResponse]{
+		arrange:    "image_tag",
+		beforeSend: []func(*Analyzer, *request) error{preprocessImage},
+	}
+	authorsEndpoint         = &endpoint[AuthorsResponse]{arrange: "authors"}
+	languageEndpoint        = &endpoint[LanguageResponse]{arrange: "language"}
+	feedsEndpoint           = &endpoint[FeedsResponse]{arrange: "feeds"}
+	microformatsEndpoint    = &endpoint[MicroFormatsResponse]{arrange: "microformats"}
+	combinedEndpoint        = &endpoint[CombinedResponse]{arrange: "combined"}
+	publicationDateEndpoint = &endpoint[PublicationDateResponse]{arrange: "publication_date"}
+)
+
+// Calls the endpoint with the payload. The extra values, e.g. a target,
+// are added to the options, the payload takes precedence over them.
+func call[T any, P responsePtr[T]](ctx context.Context, analyzer *Analyzer, e *endpoint[T], flavor, payload string, options, extra url.Values) (*T, error) {
+	if !entryPoints.hasFlavor(e.arrange, flavor) {
+		return nil, unsupportedFlavor(e.arrange, flavor)
+	}
+
+	req := newRequest(e.arrange, flavor, options)
+	for k, v := range extra {
+		req.values[k] = append([]string(nil), v...)
+	}
+	req.values.Set(flavor, payload)
+	return do[T, P](ctx, analyzer, e, req)
+}
+
+// Runs the hooks around analyze and decodes the answer
+func do[T any, P responsePtr[T]](ctx context.Context, analyzer *Analyzer, e *endpoint[T], req *request) (response *T, err error) {
+	payloadSize := len(req.values.Get(req.flavor))
+	if req.binData != nil {
+		payloadSize = len(req.binData)
+	}
+	ctx, end := analyzer.startMethod(ctx, req.arrange, req.flavor, payloadSize)
+	defer func() { end(err) }()
+
+	for _, hook := range e.beforeSend {
+		if err := hook(analyzer, req); err != nil {
+			return nil, err
+		}
+	}
+
+	data, err := analyzer.analyze(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	response = new(T)
+	if err := json.Unmarshal(data, response); err != nil {
+		return nil, err
+	}
+	if status, statusInfo := P(response).ResponseStatus(); status != "OK" {
+		return nil, newAPIError(req.arrange, req.flavor, 0, statusInfo)
+	}
+
+	for _, hook := range e.afterReceive {
+		if err := hook(analyzer, req, response); err != nil {
+			return nil, err
+		}
+	}
+	return response, nil
+}
+
+func requireTarget(analyzer *Analyzer, req *request) error {
+	if req.values.Get("target") == "" {
+		return errors.New("targeted sentiment requires a non-null target.")
+	}
+	return nil
+}
+
+func preprocessImage(analyzer *Analyzer, req *request) error {
+	if req.binData == nil {
+		return nil
+	}
+	imageData, scale, err := analyzer.preprocessImage(req.binData)
+	if err != nil {
+		return err
+	}
+	req.binData, req.scale = imageData, scale
+	return nil
+}
+
+func rescaleFaces(analyzer *Analyzer, req *request, response *FaceResponse) error {
+	req.scale.faces(response.ImageFaces)
+	return nil
+}
@@ -0,0 +1,77 @@
+package alchemyapi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestCall(t *testing.T) {
+	apiKey := "foooooooooooooooooooooooooooooooooooobar"
+	var requests int
+	var form url.Values
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		r.ParseForm()
+		form = r.PostForm
+		w.Write([]byte("{\"status\":\"OK\",\"language\":\"english\"}"))
+	}
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	analyzer, _ := NewAnalyzer(apiKey, WithBaseURL(server.URL))
+	var order []string
+	e := &endpoint[LanguageResponse]{
+		arrange: "language",
+		beforeSend: []func(*Analyzer, *request) error{
+			func(analyzer *Analyzer, req *request) error {
+				order = append(order, "before")
+				req.values.Set("hooked", "1")
+				return nil
+			},
+		},
+		afterReceive: []func(*Analyzer, *request, *LanguageResponse) error{
+			func(analyzer *Analyzer, req *request, response *LanguageResponse) error {
+				order = append(order, "after:"+response.Language)
+				return nil
+			},
+		},
+	}
+
+	extra := url.Values{"url": {"http://extra"}, "target": {"foo"}}
+	response, err := call(context.Background(), analyzer, e, "url", "http://payload", url.Values{}, extra)
+	if err != nil || response.Language != "english" {
+		t.Fatalf("unexpected response %v, %v", response, err)
+	}
+	if form.Get("hooked") != "1" || form.Get("url") != "http://payload" || form.Get("target") != "foo" {
+		t.Errorf("unexpected form %v", form)
+	}
+	if len(order) != 2 || order[0] != "before" || order[1] != "after:english" {
+		t.Errorf("unexpected hooks %v", order)
+	}
+
+	failing := errors.New("failing")
+	e.beforeSend = append(e.beforeSend, func(*Analyzer, *request) error { return failing })
+	if _, err := call(context.Background(), analyzer, e, "text", "foobar", url.Values{}, nil); err != failing {
+		t.Errorf("want %v, but %v", failing, err)
+	}
+	if _, err := call(context.Background(), analyzer, e, "image", "foobar", url.Values{}, nil); !errors.Is(err, ErrUnsupportedFlavor) {
+		t.Errorf("want %v, but %v", ErrUnsupportedFlavor, err)
+	}
+	if _, err := analyzer.SentimentTargeted("text", "foobar", "", url.Values{}); err == nil {
+		t.Error("should raise exception for an empty target")
+	}
+	if requests != 1 {
+		t.Errorf("failed calls should not be sent, but %d requests", requests)
+	}
+}
+
+func TestResponseStatus(t *testing.T) {
+	var response Response = &EntitiesResponse{Status: "ERROR", StatusInfo: "invalid-api-key"}
+	if status, statusInfo := response.ResponseStatus(); status != "ERROR" || statusInfo != "invalid-api-key" {
+		t.Errorf("unexpected status %s %s", status, statusInfo)
+	}
+}
@@ -0,0 +1,275 @@
+package alchemyapi
+
+/**
+  Copyright 2015 AlchemyAPI
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+      http://www.apache.org/licenses/LICENSE-2.0
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+	"math"
+)
+
+const (
+	DefaultImageMaxDimension = 1024
+	DefaultImageQuality      = 85
+
+	// 40 megapixels, about 160MB once decoded
+	DefaultImageMaxPixels = 40000000
+)
+
+// ImageDimensionsError is returned, without decoding nor sending anything,
+// when a preprocessed image declares more pixels than
+// ImagePreprocessing.MaxPixels. It wraps ErrContentExceedsMaxLimit.
+type ImageDimensionsError struct {
+	Width, Height int
+	Limit         int64
+}
+
+func (e *ImageDimensionsError) Error() string {
+	return fmt.Sprintf("%s: image of %dx%d pixels, limit %d pixels", ErrContentExceedsMaxLimit, e.Width, e.Height, e.Limit)
+}
+
+func (e *ImageDimensionsError) Unwrap() error {
+	return ErrContentExceedsMaxLimit
+}
+
+/*
+   ImagePreprocessing shrinks images before they are posted with the image
+   flavor: the image is decoded (JPEG, PNG or GIF), turned upright following
+   its EXIF orientation, downscaled so its longest side is at most
+   MaxDimension and re-encoded as a JPEG of the given Quality. Transparent
+   areas become white. Images in other formats are posted unchanged.
+
+   The positions and sizes of the faces found by Face are scaled back, so
+   they refer to the upright original image.
+
+   Decoding takes memory in proportion to the pixels, not to the bytes
+   limited by WithMaxImageSize: images declaring more than MaxPixels fail
+   with an *ImageDimensionsError before they are decoded.
+*/
+type ImagePreprocessing struct {
+	MaxDimension int   // DefaultImageMaxDimension if zero
+	Quality      int   // 1 to 100, DefaultImageQuality if zero
+	MaxPixels    int64 // width times height, DefaultImageMaxPixels if zero
+}
+
+// Preprocesses images posted with the image flavor, see ImagePreprocessing.
+// The limit of WithMaxImageSize applies to the original image.
+func WithImagePreprocessing(preprocessing ImagePreprocessing) Option {
+	return func(analyzer *Analyzer) {
+		if preprocessing.MaxDimension <= 0 {
+			preprocessing.MaxDimension = DefaultImageMaxDimension
+		}
+		if preprocessing.Quality <= 0 || preprocessing.Quality > 100 {
+			preprocessing.Quality = DefaultImageQuality
+		}
+		if preprocessing.MaxPixels <= 0 {
+			preprocessing.MaxPixels = DefaultImageMaxPixels
+		}
+		analyzer.preprocessing = &preprocessing
+	}
+}
+
+// How the coordinates of the preprocessed image map to the original one
+type imageScale struct {
+	x, y float64
+}
+
+// Rescales the faces found in the preprocessed image
+func (scale imageScale) faces(faces []ImageFace) {
+	for i := range faces {
+		face := &faces[i]
+		face.PositionX = Int(math.Round(float64(face.PositionX) * scale.x))
+		face.PositionY = Int(math.Round(float64(face.PositionY) * scale.y))
+		face.Width = Int(math.Round(float64(face.Width) * scale.x))
+		face.Height = Int(math.Round(float64(face.Height) * scale.y))
+	}
+}
+
+// Applies the preprocessing of the analyzer, if any, to the image
+func (analyzer *Analyzer) preprocessImage(imageData []byte) ([]byte, imageScale, error) {
+	scale := imageScale{1, 1}
+	if analyzer.preprocessing == nil {
+		return imageData, scale, nil
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(imageData))
+	if errors.Is(err, image.ErrFormat) {
+		return imageData, scale, nil
+	} else if err != nil {
+		return nil, scale, err
+	}
+	if limit := analyzer.preprocessing.MaxPixels; int64(config.Width)*int64(config.Height) > limit {
+		return nil, scale, &ImageDimensionsError{Width: config.Width, Height: config.Height, Limit: limit}
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(imageData))
+	if errors.Is(err, image.ErrFormat) {
+		return imageData, scale, nil
+	} else if err != nil {
+		return nil, scale, err
+	}
+
+	img := orient(src, exifOrientation(imageData))
+	width, height := img.Bounds().Dx(), img.Bounds().Dy()
+	if limit := analyzer.preprocessing.MaxDimension; width > limit || height > limit {
+		ratio := float64(limit) / float64(width)
+		if height > width {
+			ratio = float64(limit) / float64(height)
+		}
+		img = downscale(img, max(1, int(float64(width)*ratio)), max(1, int(float64(height)*ratio)))
+		scale.x = float64(width) / float64(img.Bounds().Dx())
+		scale.y = float64(height) / float64(img.Bounds().Dy())
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: analyzer.preprocessing.Quality}); err != nil {
+		return nil, scale, err
+	}
+	return buf.Bytes(), scale, nil
+}
+
+// The EXIF orientation (1 to 8) of a JPEG image, 1 when missing
+func exifOrientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 1
+	}
+
+	for pos := 2; pos+4 <= len(data); {
+		if data[pos] != 0xFF {
+			return 1
+		}
+		marker := data[pos+1]
+		length := int(binary.BigEndian.Uint16(data[pos+2:]))
+		if marker == 0xDA || length < 2 || pos+2+length > len(data) {
+			// start of scan, no metadata after this
+			return 1
+		}
+		segment := data[pos+4 : pos+2+length]
+		if marker == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return tiffOrientation(segment[6:])
+		}
+		pos += 2 + length
+	}
+	return 1
+}
+
+// Looks up the orientation tag in the first IFD of a TIFF header
+func tiffOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+
+	ifd := int(order.Uint32(tiff[4:]))
+	if ifd+2 > len(tiff) {
+		return 1
+	}
+	entries := int(order.Uint16(tiff[ifd:]))
+	for i := 0; i < entries; i++ {
+		entry := ifd + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+		if order.Uint16(tiff[entry:]) == 0x0112 {
+			orientation := int(order.Uint16(tiff[entry+8:]))
+			if orientation < 1 || orientation > 8 {
+				return 1
+			}
+			return orientation
+		}
+	}
+	return 1
+}
+
+// Returns the image turned upright as an RGBA image over white
+func orient(src image.Image, orientation int) *image.RGBA {
+	bounds := src.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(rgba, rgba.Bounds(), src, bounds.Min, draw.Over)
+	if orientation == 1 {
+		return rgba
+	}
+
+	w, h := bounds.Dx(), bounds.Dy()
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		for x := 0; x < dw; x++ {
+			var sx, sy int
+			switch orientation {
+			case 2:
+				sx, sy = w-1-x, y
+			case 3:
+				sx, sy = w-1-x, h-1-y
+			case 4:
+				sx, sy = x, h-1-y
+			case 5:
+				sx, sy = y, x
+			case 6:
+				sx, sy = y, h-1-x
+			case 7:
+				sx, sy = w-1-y, h-1-x
+			case 8:
+				sx, sy = w-1-y, x
+			}
+			copy(dst.Pix[dst.PixOffset(x, y):][:4], rgba.Pix[rgba.PixOffset(sx, sy):][:4])
+		}
+	}
+	return dst
+}
+
+// Shrinks the image to width x height averaging the covered source pixels
+func downscale(src *image.RGBA, width, height int) *image.RGBA {
+	sw, sh := src.Bounds().Dx(), src.Bounds().Dy()
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		y0, y1 := y*sh/height, max((y+1)*sh/height, y*sh/height+1)
+		for x := 0; x < width; x++ {
+			x0, x1 := x*sw/width, max((x+1)*sw/width, x*sw/width+1)
+			var sum [4]int
+			for sy := y0; sy < y1; sy++ {
+				row := src.Pix[src.PixOffset(x0, sy):]
+				for i := 0; i < (x1-x0)*4; i++ {
+					sum[i%4] += int(row[i])
+				}
+			}
+			n := (x1 - x0) * (y1 - y0)
+			pix := dst.Pix[dst.PixOffset(x, y):]
+			for i := range sum {
+				pix[i] = uint8((sum[i] + n/2) / n)
+			}
+		}
+	}
+	return dst
+}
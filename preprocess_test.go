@@ -0,0 +1,149 @@
+package alchemyapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+// A JPEG of the given size, red in the top left quarter, blue elsewhere,
+// tagged with the EXIF orientation unless zero
+func testJPEG(width, height, orientation int) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			if x < width/2 && y < height/2 {
+				img.Set(x, y, color.RGBA{255, 0, 0, 255})
+			} else {
+				img.Set(x, y, color.RGBA{0, 0, 255, 255})
+			}
+		}
+	}
+	var buf bytes.Buffer
+	jpeg.Encode(&buf, img, &jpeg.Options{Quality: 100})
+	if orientation == 0 {
+		return buf.Bytes()
+	}
+
+	// big endian TIFF header with a single IFD entry
+	tiff := []byte("MM\x00\x2a\x00\x00\x00\x08\x00\x01\x01\x12\x00\x03\x00\x00\x00\x01\x00\x00\x00\x00\x00\x00\x00\x00")
+	binary.BigEndian.PutUint16(tiff[18:], uint16(orientation))
+	segment := append([]byte("Exif\x00\x00"), tiff...)
+	app1 := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(app1[2:], uint16(len(segment)+2))
+	data := append([]byte{0xFF, 0xD8}, append(app1, segment...)...)
+	return append(data, buf.Bytes()[2:]...)
+}
+
+func isRed(c color.Color) bool {
+	r, g, b, _ := c.RGBA()
+	return r > 0xc000 && g < 0x4000 && b < 0x4000
+}
+
+func TestExifOrientation(t *testing.T) {
+	for _, orientation := range []int{1, 3, 6, 8} {
+		if got := exifOrientation(testJPEG(8, 8, orientation)); got != orientation {
+			t.Errorf("want %v, but %v", orientation, got)
+		}
+	}
+	if got := exifOrientation(testJPEG(8, 8, 0)); got != 1 {
+		t.Errorf("want %v, but %v", 1, got)
+	}
+	if got := exifOrientation([]byte("not a jpeg")); got != 1 {
+		t.Errorf("want %v, but %v", 1, got)
+	}
+}
+
+func TestPreprocessImage(t *testing.T) {
+	analyzer, _ := NewAnalyzer("foooooooooooooooooooooooooooooooooooobar",
+		WithImagePreprocessing(ImagePreprocessing{MaxDimension: 40}))
+
+	// rotated 90 degrees clockwise, the red quarter ends up top right
+	data, scale, err := analyzer.preprocessImage(testJPEG(160, 80, 6))
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := jpeg.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Bounds().Dx() != 20 || img.Bounds().Dy() != 40 {
+		t.Errorf("want %v, but %v", "20x40", img.Bounds())
+	}
+	if !isRed(img.At(15, 5)) || isRed(img.At(5, 5)) {
+		t.Error("the image should be turned upright")
+	}
+	if scale.x != 4 || scale.y != 4 {
+		t.Errorf("want %v, but %v", imageScale{4, 4}, scale)
+	}
+
+	// small images are only re-encoded, other formats pass through
+	var buf bytes.Buffer
+	png.Encode(&buf, image.NewGray(image.Rect(0, 0, 10, 10)))
+	data, scale, err = analyzer.preprocessImage(buf.Bytes())
+	if _, format, _ := image.Decode(bytes.NewReader(data)); err != nil || format != "jpeg" || scale.x != 1 {
+		t.Errorf("want %v, but %v", "jpeg", format)
+	}
+	data, _, err = analyzer.preprocessImage([]byte("not an image"))
+	if err != nil || string(data) != "not an image" {
+		t.Errorf("unknown formats should be sent as is, %v", err)
+	}
+}
+
+func TestPreprocessImageMaxPixels(t *testing.T) {
+	analyzer, _ := NewAnalyzer("foooooooooooooooooooooooooooooooooooobar",
+		WithImagePreprocessing(ImagePreprocessing{MaxPixels: 10000}))
+
+	// a tiny PNG declaring 100000x100000 pixels in its header
+	var buf bytes.Buffer
+	png.Encode(&buf, image.NewGray(image.Rect(0, 0, 1, 1)))
+	data := buf.Bytes()
+	binary.BigEndian.PutUint32(data[16:], 100000)
+	binary.BigEndian.PutUint32(data[20:], 100000)
+	binary.BigEndian.PutUint32(data[29:], crc32.ChecksumIEEE(data[12:29]))
+
+	_, _, err := analyzer.preprocessImage(data)
+	var dimensionsErr *ImageDimensionsError
+	if !errors.As(err, &dimensionsErr) || dimensionsErr.Width != 100000 || !errors.Is(err, ErrContentExceedsMaxLimit) {
+		t.Errorf("want %v, but %v", ErrContentExceedsMaxLimit, err)
+	}
+
+	if _, _, err := analyzer.preprocessImage(testJPEG(100, 100, 0)); err != nil {
+		t.Errorf("should not raise exception, %v", err)
+	}
+}
+
+func TestAnalyzerFacePreprocessing(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		config, err := jpeg.DecodeConfig(bytes.NewReader(body))
+		if err != nil || config.Width != 50 || config.Height != 25 {
+			t.Errorf("unexpected image %v, %v", config, err)
+		}
+		w.Write([]byte("{\"status\":\"OK\",\"imageFaces\":[{\"positionX\":\"10\",\"positionY\":\"5\",\"width\":\"20\",\"height\":\"10\"}]}"))
+	}
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	analyzer, _ := NewAnalyzer("foooooooooooooooooooooooooooooooooooobar", WithBaseURL(server.URL),
+		WithImagePreprocessing(ImagePreprocessing{MaxDimension: 50, Quality: 70}))
+	response, err := analyzer.FaceFromBytes(context.Background(), testJPEG(200, 100, 0), url.Values{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	face := response.ImageFaces[0]
+	if face.PositionX != 40 || face.PositionY != 20 || face.Width != 80 || face.Height != 40 {
+		t.Errorf("want %v, but %v", "40 20 80 40", face)
+	}
+}
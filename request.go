@@ -0,0 +1,68 @@
+package alchemyapi
+
+/**
+  Copyright 2015 AlchemyAPI
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+      http://www.apache.org/licenses/LICENSE-2.0
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+import (
+	"net/http"
+	"net/url"
+)
+
+// request is a single AlchemyAPI call. It owns a copy of the caller's
+// options, so the caller may reuse or share them between goroutines. The
+// Analyzer method and the before-send hooks of the endpoint fill it in,
+// once sent it is never modified.
+type request struct {
+	arrange string
+	flavor  string
+	values  url.Values  // the options plus the payload, never the api key
+	binData []byte      // the image for the image flavor, nil otherwise
+	scale   imageScale  // of the image, after preprocessing
+	header  http.Header // set by interceptors, nil otherwise
+	apiKey  string      // set by interceptors, the analyzer's key if empty
+}
+
+func newRequest(arrange, flavor string, options url.Values) *request {
+	return &request{
+		arrange: arrange,
+		flavor:  flavor,
+		values:  copyValues(options),
+		scale:   imageScale{1, 1},
+	}
+}
+
+// The values sent along, a fresh copy holding the api key
+func (req *request) form(apiKey string) url.Values {
+	form := copyValues(req.values)
+	form.Set("apikey", apiKey)
+	form.Set("outputMode", "json")
+	return form
+}
+
+// The url and body to post
+func (req *request) encode(baseUrl, apiKey string) (string, []byte) {
+	url := entryPoints.urlFor(baseUrl, req.arrange, req.flavor)
+	form := req.form(apiKey).Encode()
+	if req.binData == nil {
+		return url, []byte(form)
+	}
+	return url + "?" + form, req.binData
+}
+
+func copyValues(values url.Values) url.Values {
+	copied := make(url.Values, len(values)+3)
+	for k, v := range values {
+		copied[k] = append([]string(nil), v...)
+	}
+	return copied
+}
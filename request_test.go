@@ -0,0 +1,73 @@
+package alchemyapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+)
+
+func TestRequestForm(t *testing.T) {
+	options := url.Values{"sentiment": {"1"}}
+	req := newRequest("entities", "text", options)
+	req.values.Set("text", "foobar")
+
+	form := req.form("secret")
+	if form.Get("apikey") != "secret" || form.Get("outputMode") != "json" || form.Get("text") != "foobar" {
+		t.Errorf("unexpected form %v", form)
+	}
+	if req.values.Get("apikey") != "" {
+		t.Error("the request should never hold the api key")
+	}
+	if len(options) != 1 {
+		t.Errorf("the options should be untouched, but %v", options)
+	}
+
+	req.binData = []byte("image")
+	url, body := req.encode("http://host", "secret")
+	if string(body) != "image" || url != "http://host/text/TextGetRankedNamedEntities?"+form.Encode() {
+		t.Errorf("unexpected url %s", url)
+	}
+}
+
+func TestAnalyzerSharedOptions(t *testing.T) {
+	apiKey := "foooooooooooooooooooooooooooooooooooobar"
+	var mu sync.Mutex
+	var bad []string
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if len(r.PostForm["text"]) != 1 || len(r.PostForm["apikey"]) != 1 {
+			mu.Lock()
+			bad = append(bad, r.PostForm.Encode())
+			mu.Unlock()
+		}
+		w.Write([]byte("{\"status\":\"OK\"}"))
+	}
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	analyzer, _ := NewAnalyzer(apiKey, WithBaseURL(server.URL))
+	shared := url.Values{"sentiment": {"1"}, "maxRetrieve": {"10"}}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if i%2 == 0 {
+				analyzer.Entities("text", "foobar", shared)
+			} else {
+				analyzer.Keywords("text", "foobar", shared)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if len(bad) != 0 {
+		t.Errorf("every call should send a single payload and key, but %v", bad)
+	}
+	if len(shared) != 2 || shared.Get("text") != "" || shared.Get("apikey") != "" {
+		t.Errorf("the shared options should be untouched, but %v", shared)
+	}
+}
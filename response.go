@@ -28,14 +28,14 @@ type Concept struct {
 	} `json:"knowledgeGraph"`
 	MusicBrainz string `json:"musicBrainz"`
 	Opencyc     string `json:"opencyc"`
-	Relevance   string `json:"relevance"`
+	Relevance   Float  `json:"relevance"`
 	Text        string `json:"text"`
 	Website     string `json:"website"`
 	Yago        string `json:"yago"`
 }
 
 type Entity struct {
-	Count         string `json:"count"`
+	Count         Int `json:"count"`
 	Disambiguated struct {
 		Census      string   `json:"census"`
 		CiaFactbook string   `json:"ciaFactbook"`
@@ -58,7 +58,7 @@ type Entity struct {
 	Quotations []struct {
 		Quotation string `json:"quotation"`
 	} `json:"quotations"`
-	Relevance string    `json:"relevance"`
+	Relevance Float     `json:"relevance"`
 	Sentiment Sentiment `json:"sentiment"`
 	Text      string    `json:"text"`
 	Type      string    `json:"type"`
@@ -70,14 +70,14 @@ type Feed struct {
 
 type ImageFace struct {
 	Age struct {
-		AgeRange string  `json:"ageRange"`
-		Score    float64 `json:"score,string"`
+		AgeRange string `json:"ageRange"`
+		Score    Float  `json:"score"`
 	} `json:"age"`
 	Gender struct {
-		Gender string  `json:"gender"`
-		Score  float64 `json:"score,string"`
+		Gender string `json:"gender"`
+		Score  Float  `json:"score"`
 	} `json:"gender"`
-	Height   int64 `json:"height,string"`
+	Height   Int `json:"height"`
 	Identity struct {
 		Disambiguated struct {
 			Crunchbase  string   `json:"crunchbase"`
@@ -93,19 +93,19 @@ type ImageFace struct {
 		KnowledgeGraph struct {
 			TypeHierarchy string `json:"typeHierarchy"`
 		} `json:"knowledgeGraph"`
-		Name  string  `json:"name"`
-		Score float64 `json:"score,string"`
+		Name  string `json:"name"`
+		Score Float  `json:"score"`
 	} `json:"identity"`
-	PositionX int64 `json:"positionX,string"`
-	PositionY int64 `json:"positionY,string"`
-	Width     int64 `json:"width,string"`
+	PositionX Int `json:"positionX"`
+	PositionY Int `json:"positionY"`
+	Width     Int `json:"width"`
 }
 
 type ImageKeyword struct {
 	KnowledgeGraph struct {
 		TypeHierarchy string `json:"typeHierarchy"`
 	} `json:"knowledgeGraph"`
-	Score string `json:"score"`
+	Score Float  `json:"score"`
 	Text  string `json:"text"`
 }
 
@@ -113,7 +113,7 @@ type Keyword struct {
 	KnowledgeGraph struct {
 		TypeHierarchy string `json:"typeHierarchy"`
 	} `json:"knowledgeGraph"`
-	Relevance string    `json:"relevance"`
+	Relevance Float     `json:"relevance"`
 	Sentiment Sentiment `json:"sentiment"`
 	Text      string    `json:"text"`
 }
@@ -147,19 +147,19 @@ type Relation struct {
 }
 
 type Sentiment struct {
-	Mixed int64   `json:"mixed,string"`
-	Score float64 `json:"score,string"`
-	Type  string  `json:"type"`
+	Mixed Int    `json:"mixed"`
+	Score Float  `json:"score"`
+	Type  string `json:"type"`
 }
 
 type Taxonomy struct {
-	Confident string  `json:"confident"`
-	Label     string  `json:"label"`
-	Score     float64 `json:"score,string"`
+	Confident Bool   `json:"confident"`
+	Label     string `json:"label"`
+	Score     Float  `json:"score"`
 }
 
 type PublicationDate struct {
-	Confident string `json:"confident"`
+	Confident Bool   `json:"confident"`
 	Date      string `json:"date"`
 }
 
@@ -170,7 +170,7 @@ type SentimentResponse struct {
 	Status            string    `json:"status"`
 	StatusInfo        string    `json:"statusInfo,omitempty"`
 	Text              string    `json:"text,omitempty"`
-	TotalTransactions int64     `json:"totalTransactions,string"`
+	TotalTransactions Int       `json:"totalTransactions"`
 	Url               string    `json:"url,omitempty"`
 	Usage             string    `json:"usage,omitempty"`
 }
@@ -182,7 +182,7 @@ type TaxonomyResponse struct {
 	StatusInfo        string     `json:"statusInfo,omitempty"`
 	Taxonomies        []Taxonomy `json:"taxonomy"`
 	Text              string     `json:"text,omitempty"`
-	TotalTransactions int64      `json:"totalTransactions,string"`
+	TotalTransactions Int        `json:"totalTransactions"`
 	Url               string     `json:"url,omitempty"`
 	Usage             string     `json:"usage,omitempty"`
 }
@@ -205,7 +205,7 @@ type EntitiesResponse struct {
 	Status            string   `json:"status"`
 	StatusInfo        string   `json:"statusInfo,omitempty"`
 	Text              string   `json:"text,omitempty"`
-	TotalTransactions int64    `json:"totalTransactions,string"`
+	TotalTransactions Int      `json:"totalTransactions"`
 	Url               string   `json:"url,omitempty"`
 	Usage             string   `json:"usage,omitempty"`
 }
@@ -247,7 +247,7 @@ type FaceResponse struct {
 	ImageFaces        []ImageFace `json:"imageFaces"`
 	Status            string      `json:"status"`
 	StatusInfo        string      `json:"statusInfo,omitempty"`
-	TotalTransactions int64       `json:"totalTransactions,string"`
+	TotalTransactions Int         `json:"totalTransactions"`
 	Url               string      `json:"url,omitempty"`
 	Usage             string      `json:"usage"`
 }
@@ -268,7 +268,7 @@ type ImageTagResponse struct {
 	Language          string         `json:"language"`
 	Status            string         `json:"status"`
 	StatusInfo        string         `json:"statusInfo,omitempty"`
-	TotalTransactions int64          `json:"totalTransactions,string"`
+	TotalTransactions Int            `json:"totalTransactions"`
 	Url               string         `json:"url,omitempty"`
 	Usage             string         `json:"usage,omitempty"`
 }
@@ -276,7 +276,7 @@ type ImageTagResponse struct {
 // AuthorsResponse
 type AuthorsResponse struct {
 	Authors struct {
-		Confident string   `json:"confident"`
+		Confident Bool     `json:"confident"`
 		Names     []string `json:"names"`
 	} `json:"authors"`
 	Status     string `json:"status"`
@@ -327,7 +327,7 @@ type CombinedResponse struct {
 	Feeds             []Feed          `json:"feeds"`
 	Image             string          `json:"image"`
 	ImageKeywords     []ImageKeyword  `json:"imageKeywords"`
-	Keywords          []Keyword       `json:"keywords`
+	Keywords          []Keyword       `json:"keywords"`
 	Language          string          `json:"language"`
 	PublicationDate   PublicationDate `json:"publicationDate"`
 	Relations         []Relation      `json:"relations"`
@@ -335,7 +335,7 @@ type CombinedResponse struct {
 	StatusInfo        string          `json:"statusInfo,omitempty"`
 	Taxonomies        []Taxonomy      `json:"taxonomy"`
 	Title             string          `json:"title"`
-	TotalTransactions int64           `json:"totalTransactions,string"`
+	TotalTransactions Int             `json:"totalTransactions"`
 	Url               string          `json:"url"`
 	Usage             string          `json:"usage"`
 }
@@ -348,3 +348,83 @@ type PublicationDateResponse struct {
 	Url             string          `json:"url"`
 	Usage           string          `json:"usage"`
 }
+
+func (response *SentimentResponse) ResponseStatus() (string, string) {
+	return response.Status, response.StatusInfo
+}
+
+func (response *TaxonomyResponse) ResponseStatus() (string, string) {
+	return response.Status, response.StatusInfo
+}
+
+func (response *ConceptsResponse) ResponseStatus() (string, string) {
+	return response.Status, response.StatusInfo
+}
+
+func (response *EntitiesResponse) ResponseStatus() (string, string) {
+	return response.Status, response.StatusInfo
+}
+
+func (response *KeywordsResponse) ResponseStatus() (string, string) {
+	return response.Status, response.StatusInfo
+}
+
+func (response *RelationsResponse) ResponseStatus() (string, string) {
+	return response.Status, response.StatusInfo
+}
+
+func (response *TextTitleResponse) ResponseStatus() (string, string) {
+	return response.Status, response.StatusInfo
+}
+
+func (response *FaceResponse) ResponseStatus() (string, string) {
+	return response.Status, response.StatusInfo
+}
+
+func (response *ImageExtractResponse) ResponseStatus() (string, string) {
+	return response.Status, response.StatusInfo
+}
+
+func (response *ImageTagResponse) ResponseStatus() (string, string) {
+	return response.Status, response.StatusInfo
+}
+
+func (response *AuthorsResponse) ResponseStatus() (string, string) {
+	return response.Status, response.StatusInfo
+}
+
+func (response *LanguageResponse) ResponseStatus() (string, string) {
+	return response.Status, response.StatusInfo
+}
+
+func (response *FeedsResponse) ResponseStatus() (string, string) {
+	return response.Status, response.StatusInfo
+}
+
+func (response *MicroFormatsResponse) ResponseStatus() (string, string) {
+	return response.Status, response.StatusInfo
+}
+
+func (response *CombinedResponse) ResponseStatus() (string, string) {
+	return response.Status, response.StatusInfo
+}
+
+func (response *PublicationDateResponse) ResponseStatus() (string, string) {
+	return response.Status, response.StatusInfo
+}
+
+// The fields shared by every response
+type statusEnvelope struct {
+	Status            string `json:"status"`
+	StatusInfo        string `json:"statusInfo,omitempty"`
+	Language          string `json:"language,omitempty"`
+	TotalTransactions Int    `json:"totalTransactions,omitempty"`
+}
+
+// The transactions charged for the call, 1 when not reported
+func (status *statusEnvelope) transactions() int64 {
+	if n := int64(status.TotalTransactions); n > 0 {
+		return n
+	}
+	return 1
+}
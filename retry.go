@@ -0,0 +1,137 @@
+package alchemyapi
+
+/**
+  Copyright 2015 AlchemyAPI
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+      http://www.apache.org/licenses/LICENSE-2.0
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// RetryPolicy controls how failed calls are re-sent, see WithRetry.
+type RetryPolicy struct {
+	MaxAttempts int              // total attempts including the first one, 1 or less disables retrying
+	BaseDelay   time.Duration    // delay before the second attempt, doubled for every further attempt
+	MaxDelay    time.Duration    // upper bound of a single delay, 0 means unbounded
+	Jitter      float64          // fraction (0..1) of every delay that is randomized
+	Retryable   func(error) bool // which errors are retried, nil means IsRetryable
+}
+
+// A sane policy for batch workers: 3 attempts, 200ms, 400ms.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   200 * time.Millisecond,
+	MaxDelay:    5 * time.Second,
+	Jitter:      0.2,
+}
+
+// Retries failed calls according to the policy.
+func WithRetry(policy RetryPolicy) Option {
+	return func(analyzer *Analyzer) {
+		analyzer.retry = policy
+	}
+}
+
+// RetryError is returned once every attempt of a call with a retry policy
+// failed, Err is the error of the last attempt.
+type RetryError struct {
+	Attempts int
+	Err      error
+}
+
+func (e *RetryError) Error() string {
+	return fmt.Sprintf("%s (after %d attempts)", e.Err, e.Attempts)
+}
+
+func (e *RetryError) Unwrap() error {
+	return e.Err
+}
+
+// Reports whether the error is worth another attempt: network failures,
+// 5xx and 429 responses, and throttling statusInfo values.
+func IsRetryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, ErrCassetteMiss) {
+		return false
+	}
+
+	if errors.Is(err, ErrThrottled) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+
+	var transportErr *TransportError
+	if errors.As(err, &transportErr) {
+		return transportErr.StatusCode >= 500 || transportErr.StatusCode == http.StatusTooManyRequests
+	}
+
+	var urlErr *url.Error
+	return errors.As(err, &urlErr)
+}
+
+func (policy RetryPolicy) enabled() bool {
+	return policy.MaxAttempts > 1
+}
+
+func (policy RetryPolicy) retryable(err error) bool {
+	if policy.Retryable != nil {
+		return policy.Retryable(err)
+	}
+	return IsRetryable(err)
+}
+
+// The delay before the given (next) attempt, attempt starts at 2
+func (policy RetryPolicy) delay(attempt int) time.Duration {
+	delay := policy.BaseDelay
+	for i := 2; i < attempt; i++ {
+		delay *= 2
+		if policy.MaxDelay > 0 && delay >= policy.MaxDelay {
+			break
+		}
+	}
+	if policy.MaxDelay > 0 && delay > policy.MaxDelay {
+		delay = policy.MaxDelay
+	}
+
+	if policy.Jitter > 0 {
+		jitter := policy.Jitter
+		if jitter > 1 {
+			jitter = 1
+		}
+		spread := float64(delay) * jitter
+		delay = time.Duration(float64(delay) - spread + rand.Float64()*spread)
+	}
+	return delay
+}
+
+// Waits for the delay or until the context is done
+func sleepContext(ctx context.Context, delay time.Duration) error {
+	if delay <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,146 @@
+package alchemyapi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicyDelay(t *testing.T) {
+	policy := RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: 300 * time.Millisecond}
+	cases := map[int]time.Duration{
+		2: 100 * time.Millisecond,
+		3: 200 * time.Millisecond,
+		4: 300 * time.Millisecond,
+		9: 300 * time.Millisecond,
+	}
+	for attempt, want := range cases {
+		if got := policy.delay(attempt); got != want {
+			t.Errorf("attempt %d want %v, but %v", attempt, want, got)
+		}
+	}
+
+	policy.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		if got := policy.delay(2); got < 50*time.Millisecond || got > 100*time.Millisecond {
+			t.Fatalf("jittered delay %v out of range", got)
+		}
+	}
+}
+
+func TestAnalyzerRetry(t *testing.T) {
+	apiKey := "foooooooooooooooooooooooooooooooooooobar"
+	var calls int32
+	var bodies []string
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		bodies = append(bodies, r.PostForm.Encode())
+		switch atomic.AddInt32(&calls, 1) {
+		case 1:
+			w.WriteHeader(http.StatusBadGateway)
+			w.Write([]byte("<html>bad gateway</html>"))
+		case 2:
+			w.Write([]byte("{\"status\":\"ERROR\",\"statusInfo\":\"server-busy\"}"))
+		default:
+			w.Write([]byte("{\"status\":\"OK\",\"language\":\"english\"}"))
+		}
+	}
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	policy := RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond}
+	analyzer, _ := NewAnalyzer(apiKey, WithBaseURL(server.URL), WithRetry(policy))
+	resp, err := analyzer.Language("text", "foobar", url.Values{})
+	if err != nil {
+		t.Fatalf("should not raise exception, %v", err)
+	}
+	if resp.Language != "english" {
+		t.Errorf("want %s, but %s", "english", resp.Language)
+	}
+	if calls != 3 {
+		t.Errorf("want %d, but %d", 3, calls)
+	}
+	for _, body := range bodies {
+		if body != bodies[0] {
+			t.Errorf("every attempt should send the same body, want %s, but %s", bodies[0], body)
+		}
+	}
+}
+
+func TestAnalyzerRetryExhausted(t *testing.T) {
+	apiKey := "foooooooooooooooooooooooooooooooooooobar"
+	var calls int32
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	policy := RetryPolicy{MaxAttempts: 4, BaseDelay: time.Millisecond}
+	analyzer, _ := NewAnalyzer(apiKey, WithBaseURL(server.URL), WithRetry(policy))
+	_, err := analyzer.Language("text", "foobar", url.Values{})
+
+	var retryErr *RetryError
+	if !errors.As(err, &retryErr) {
+		t.Fatalf("should be a *RetryError, but %v", err)
+	}
+	if retryErr.Attempts != 4 || calls != 4 {
+		t.Errorf("want %d attempts, but %d (%d calls)", 4, retryErr.Attempts, calls)
+	}
+
+	var transportErr *TransportError
+	if !errors.As(err, &transportErr) || transportErr.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("should wrap the 503 transport error, but %v", err)
+	}
+}
+
+func TestAnalyzerRetryNotRetryable(t *testing.T) {
+	apiKey := "foooooooooooooooooooooooooooooooooooobar"
+	var calls int32
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.Write([]byte("{\"status\":\"ERROR\",\"statusInfo\":\"invalid-api-key\"}"))
+	}
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	analyzer, _ := NewAnalyzer(apiKey, WithBaseURL(server.URL), WithRetry(DefaultRetryPolicy))
+	_, err := analyzer.Language("text", "foobar", url.Values{})
+	if !errors.Is(err, ErrInvalidAPIKey) {
+		t.Errorf("want %v, but %v", ErrInvalidAPIKey, err)
+	}
+	if calls != 1 {
+		t.Errorf("want %d, but %d", 1, calls)
+	}
+}
+
+func TestAnalyzerRetryContextCancel(t *testing.T) {
+	apiKey := "foooooooooooooooooooooooooooooooooooobar"
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	policy := RetryPolicy{MaxAttempts: 10, BaseDelay: time.Hour}
+	analyzer, _ := NewAnalyzer(apiKey, WithBaseURL(server.URL), WithRetry(policy))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := analyzer.LanguageContext(ctx, "text", "foobar", url.Values{})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("want %v, but %v", context.DeadlineExceeded, err)
+	}
+
+	var retryErr *RetryError
+	if !errors.As(err, &retryErr) || retryErr.Attempts != 1 {
+		t.Errorf("want 1 attempt, but %v", err)
+	}
+}
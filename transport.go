@@ -0,0 +1,152 @@
+package alchemyapi
+
+/**
+  Copyright 2015 AlchemyAPI
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+      http://www.apache.org/licenses/LICENSE-2.0
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// The default limit of a response body read into memory
+	DefaultMaxResponseSize = 16 << 20
+
+	// Bytes of the body kept in a TransportError
+	snippetSize = 256
+
+	// Unread bytes discarded before closing a body, so the connection can
+	// be reused; larger leftovers close the connection instead
+	maxDrainSize = 64 << 10
+)
+
+var (
+	ErrResponseTooLarge = errors.New("response exceeds size limit")
+	ErrNotJSON          = errors.New("response is not JSON")
+)
+
+// Limits the (decompressed) size of the response bodies read into memory,
+// DefaultMaxResponseSize by default. Zero or less disables the limit.
+func WithMaxResponseSize(max int64) Option {
+	return func(analyzer *Analyzer) {
+		analyzer.maxResponseSize = max
+	}
+}
+
+// Reads the body of the response, decompressing gzip, at most
+// maxResponseSize bytes of it. The body is always drained and closed.
+func (analyzer *Analyzer) readBody(resp *http.Response) ([]byte, error) {
+	defer drainBody(resp.Body)
+
+	var body io.Reader = resp.Body
+	if resp.Header.Get("Content-Encoding") == "gzip" {
+		reader, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			return nil, newTransportError(resp, nil, err)
+		}
+		defer reader.Close()
+		body = reader
+	}
+
+	if analyzer.maxResponseSize <= 0 {
+		return io.ReadAll(body)
+	}
+	data, err := io.ReadAll(io.LimitReader(body, analyzer.maxResponseSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > analyzer.maxResponseSize {
+		err := fmt.Errorf("%w: more than %d bytes", ErrResponseTooLarge, analyzer.maxResponseSize)
+		return nil, newTransportError(resp, data, err)
+	}
+	return data, nil
+}
+
+func drainBody(body io.ReadCloser) {
+	io.Copy(io.Discard, io.LimitReader(body, maxDrainSize))
+	body.Close()
+}
+
+// Reports whether the Content-Type may hold the JSON answer of AlchemyAPI.
+// Plain text and a missing type are accepted, which is what servers
+// sniffing the body send; html and the like are not.
+func jsonContentType(contentType string) bool {
+	if contentType == "" {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	switch mediaType {
+	case "application/json", "text/json", "text/plain", "application/javascript", "text/javascript":
+		return true
+	}
+	return strings.HasSuffix(mediaType, "+json")
+}
+
+// TransportError is returned when the response is not an AlchemyAPI
+// answer, e.g. the html page of a 502 from a proxy, a body which is not
+// JSON or one over the size limit.
+type TransportError struct {
+	StatusCode  int
+	Status      string
+	ContentType string
+	Snippet     string // the start of the body, for telling what answered
+	Err         error  // the underlying failure, if any
+}
+
+func (e *TransportError) Error() string {
+	msg := fmt.Sprintf("unexpected response, http status %s", e.Status)
+	if e.ContentType != "" {
+		msg += ", content type " + e.ContentType
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	if e.Snippet != "" {
+		msg += fmt.Sprintf(", body %q", e.Snippet)
+	}
+	return msg
+}
+
+func (e *TransportError) Unwrap() error {
+	return e.Err
+}
+
+func newTransportError(resp *http.Response, data []byte, err error) *TransportError {
+	return &TransportError{
+		StatusCode:  resp.StatusCode,
+		Status:      resp.Status,
+		ContentType: resp.Header.Get("Content-Type"),
+		Snippet:     snippet(data),
+		Err:         err,
+	}
+}
+
+// The start of the body as valid text
+func snippet(data []byte) string {
+	if len(data) > snippetSize {
+		data = data[:snippetSize]
+		for len(data) > 0 && !utf8.Valid(data) {
+			data = data[:len(data)-1]
+		}
+	}
+	return strings.TrimSpace(string(data))
+}
@@ -0,0 +1,126 @@
+package alchemyapi
+
+import (
+	"compress/gzip"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestAnalyzerTransportErrors(t *testing.T) {
+	apiKey := "foooooooooooooooooooooooooooooooooooobar"
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		switch r.PostForm.Get("text") {
+		case "proxy":
+			w.Header().Set("Content-Type", "text/html")
+			w.WriteHeader(http.StatusBadGateway)
+			w.Write([]byte("<html><body>502 Bad Gateway" + strings.Repeat(" ", 1000) + "</body></html>"))
+		case "portal":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Write([]byte("<html>login</html>"))
+		case "garbage":
+			w.Write([]byte("not json"))
+		case "gzip":
+			w.Header().Set("Content-Encoding", "gzip")
+			w.Write([]byte("{\"status\":\"OK\"}"))
+		case "large":
+			w.Write([]byte("{\"status\":\"OK\",\"text\":\"" + strings.Repeat("a", 3000) + "\"}"))
+		case "throttled":
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte("{\"status\":\"ERROR\",\"statusInfo\":\"rate-limit-exceeded\"}"))
+		case "gateway":
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadGateway)
+			w.Write([]byte("{\"message\":\"bad gateway\"}"))
+		case "unavailable":
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte("{\"status\":\"OK\"}"))
+		default:
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Content-Encoding", "gzip")
+			gz := gzip.NewWriter(w)
+			gz.Write([]byte("{\"status\":\"OK\",\"language\":\"english\"}"))
+			gz.Close()
+		}
+	}
+	var conns int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(handler))
+	server.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&conns, 1)
+		}
+	}
+	server.Start()
+	defer server.Close()
+
+	analyzer, _ := NewAnalyzer(apiKey, WithBaseURL(server.URL), WithMaxResponseSize(2048))
+
+	var transportErr *TransportError
+	_, err := analyzer.Language("text", "proxy", url.Values{})
+	if !errors.As(err, &transportErr) || transportErr.StatusCode != 502 || transportErr.ContentType != "text/html" {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if !strings.HasPrefix(transportErr.Snippet, "<html><body>502 Bad Gateway") || len(transportErr.Snippet) > snippetSize {
+		t.Errorf("unexpected snippet %q", transportErr.Snippet)
+	}
+	if errors.Is(err, ErrResponseTooLarge) || !IsRetryable(err) || ErrorClass(err) != "transport" {
+		t.Errorf("a 502 should be retryable, %v", err)
+	}
+
+	for _, payload := range []string{"portal", "garbage"} {
+		_, err = analyzer.Language("text", payload, url.Values{})
+		if !errors.Is(err, ErrNotJSON) || IsRetryable(err) {
+			t.Errorf("want %v, but %v", ErrNotJSON, err)
+		}
+	}
+
+	_, err = analyzer.Language("text", "gzip", url.Values{})
+	if !errors.Is(err, gzip.ErrHeader) {
+		t.Errorf("want %v, but %v", gzip.ErrHeader, err)
+	}
+
+	_, err = analyzer.Language("text", "large", url.Values{})
+	if !errors.Is(err, ErrResponseTooLarge) {
+		t.Errorf("want %v, but %v", ErrResponseTooLarge, err)
+	}
+
+	_, err = analyzer.Language("text", "throttled", url.Values{})
+	if !errors.Is(err, ErrThrottled) {
+		t.Errorf("want %v, but %v", ErrThrottled, err)
+	}
+
+	_, err = analyzer.Language("text", "unavailable", url.Values{})
+	if !errors.As(err, &transportErr) || transportErr.StatusCode != 503 {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	var apiErr *APIError
+	_, err = analyzer.Language("text", "gateway", url.Values{})
+	if !errors.As(err, &transportErr) || transportErr.StatusCode != 502 || errors.As(err, &apiErr) || !IsRetryable(err) {
+		t.Errorf("the JSON of a gateway should be a retryable transport error, but %v", err)
+	}
+
+	retrying, _ := NewAnalyzer(apiKey, WithBaseURL(server.URL), WithRetry(RetryPolicy{MaxAttempts: 2}))
+	var retryErr *RetryError
+	_, err = retrying.Language("text", "gateway", url.Values{})
+	if !errors.As(err, &retryErr) || retryErr.Attempts != 2 {
+		t.Errorf("want %v attempts, but %v", 2, err)
+	}
+
+	response, err := analyzer.Language("text", "foobar", url.Values{})
+	if err != nil || response.Language != "english" {
+		t.Errorf("unexpected response %v, %v", response, err)
+	}
+
+	if conns != 1 {
+		t.Errorf("the connection should be reused, but %d connections", conns)
+	}
+}